@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"internal.company/NCCU-Pro/engine"
+)
+
+// --- API 錯誤格式 ---
+
+// 錯誤代碼 (供前端或整合腳本判斷錯誤類型)
+const (
+	errCodeInvalidForm       = "invalid_form"
+	errCodeMissingFile       = "missing_file"
+	errCodeUploadTooLarge    = "upload_too_large"
+	errCodeInvalidTranscript = "invalid_transcript"
+	errCodeMissingProgramIDs = "missing_program_ids"
+	errCodeProgramNotFound   = "program_not_found"
+	errCodeUnknownPrograms   = "unknown_programs"
+	errCodeRateLimited       = "rate_limited"
+	errCodeServerBusy        = "server_busy"
+)
+
+// 成績檔解析警告與衝突的數量 (成功回應的標頭)
+const transcriptWarningsHeader = "X-Transcript-Warnings"
+
+// 單一項目的錯誤 (例如批次檢核中某個學程 ID 無效)
+type APIItemError struct {
+	Index   int    `json:"index"`
+	Value   string `json:"value"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// API 統一錯誤格式
+type APIError struct {
+	Status  int            `json:"-"`
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Field   string         `json:"field,omitempty"`
+	Errors  []APIItemError `json:"errors,omitempty"`
+
+	reason string // 細分原因，僅寫入請求記錄 (不回傳給用戶端)
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// 錯誤回應的外層結構：{"error": {...}}
+type APIErrorResponse struct {
+	Error *APIError `json:"error"`
+}
+
+func newAPIError(status int, code, message, field string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message, Field: field}
+}
+
+// 輔助函式：以 JSON 格式回傳資料
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// 輔助函式：以統一錯誤格式回傳錯誤
+func writeError(w http.ResponseWriter, err *APIError) {
+	if rec, ok := w.(*statusRecorder); ok {
+		rec.errCode, rec.errReason = err.Code, err.reason
+	}
+	writeJSON(w, err.Status, APIErrorResponse{Error: err})
+}
+
+// 舊路徑 /api/check 與 /api/recommend 的錯誤格式：純文字訊息與 400 (錯誤代碼仍供記錄與監控指標使用)
+func writeLegacyError(w http.ResponseWriter, err *APIError) {
+	if rec, ok := w.(*statusRecorder); ok {
+		rec.errCode, rec.errReason = err.Code, err.reason
+	}
+	http.Error(w, err.Message, http.StatusBadRequest)
+}
+
+// --- 路由表 ---
+
+// 表單或查詢參數說明 (用於產生 OpenAPI 文件)
+type formField struct {
+	Name        string
+	Description string
+	Required    bool
+	IsFile      bool
+	Multiple    bool // 可重複上傳多個檔案
+}
+
+var studentJSONField = formField{Name: "student_json", Description: "成績檔：全人系統匯出的「課業學習」JSON、課程列表 JSON 或 CSV (依內容自動判斷)；可上傳多個檔案，將依序合併", Required: true, IsFile: true, Multiple: true}
+
+// API 路由定義
+type apiRoute struct {
+	Method      string
+	Path        string // 相對於 API 前綴的路徑
+	Handler     http.HandlerFunc
+	Legacy      http.HandlerFunc // 舊路徑 /api 回傳格式不同時使用的處理函式
+	OperationID string
+	Summary     string
+	Query       []formField
+	Form        []formField
+	Response    any      // 成功時回傳的型別 (零值)
+	Formats     []string // 可依 format 參數或 Accept 標頭選擇的回傳格式 (未設定時僅 JSON)
+	Errors      []int    // 可能回傳的錯誤狀態碼
+}
+
+var apiRoutes = []apiRoute{
+	{
+		Method:      "GET",
+		Path:        "/programs",
+		Handler:     listProgramsHandler,
+		Legacy:      getPrograms,
+		OperationID: "listPrograms",
+		Summary:     "學程列表與搜尋",
+		Query: []formField{
+			{Name: "q", Description: "名稱或說明的關鍵字，以空白分隔"},
+			{Name: "type", Description: "學程類型：micro、credit 或 specialty"},
+			{Name: "college", Description: "所屬學院"},
+			{Name: "course", Description: "包含此課程的學程"},
+		},
+		Response: []engine.ProgramSummary{},
+	},
+	{
+		Method:      "GET",
+		Path:        "/departments",
+		Handler:     listDepartmentsHandler,
+		OperationID: "listDepartments",
+		Summary:     "系所列表 (含代碼、舊名、所屬學院與學制)",
+		Query: []formField{
+			{Name: "college", Description: "學院代碼或名稱"},
+			{Name: "name", Description: "系所名稱、代碼或舊名；附加學制 (例如碩士班、在職專班) 時解析為該系所"},
+		},
+		Response: []engine.Department{},
+	},
+	{
+		Method:      "GET",
+		Path:        "/programs/{id}",
+		Handler:     getProgramHandler,
+		OperationID: "getProgram",
+		Summary:     "單一學程的認列要求與特殊規則",
+		Response:    engine.ProgramDetail{},
+		Errors:      []int{http.StatusNotFound},
+	},
+	{
+		Method:      "POST",
+		Path:        "/programs/{id}/check",
+		Handler:     checkSingleProgramHandler,
+		OperationID: "checkProgram",
+		Summary:     "檢核單一學程",
+		Form:        []formField{studentJSONField, formatField(checkFormats)},
+		Response:    engine.CheckResult{},
+		Formats:     checkFormats,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity, http.StatusServiceUnavailable},
+	},
+	{
+		Method:      "POST",
+		Path:        "/check",
+		Handler:     checkProgramsHandler,
+		Legacy:      legacyCheckProgramsHandler,
+		OperationID: "checkPrograms",
+		Summary:     "檢核多個學程",
+		Form: []formField{
+			studentJSONField,
+			{Name: "program_ids", Description: "以逗號分隔的學程 ID", Required: true},
+			formatField(checkFormats),
+		},
+		Response: []engine.CheckResult{},
+		Formats:  checkFormats,
+		Errors:   []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity, http.StatusServiceUnavailable},
+	},
+	{
+		Method:      "POST",
+		Path:        "/courses/programs",
+		Handler:     courseContributionsHandler,
+		OperationID: "courseContributions",
+		Summary:     "列出每門已通過課程可認列於哪些學程與分類",
+		Form:        []formField{studentJSONField},
+		Response:    []engine.CourseContribution{},
+		Errors:      []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity},
+	},
+	{
+		Method:      "POST",
+		Path:        "/transcript",
+		Handler:     parseTranscriptHandler,
+		OperationID: "parseTranscript",
+		Summary:     "解析並合併成績檔，列出合併後的課程與成績衝突",
+		Form:        []formField{studentJSONField},
+		Response:    engine.Transcript{},
+		Errors:      []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity},
+	},
+	{
+		Method:      "POST",
+		Path:        "/recommend",
+		Handler:     recommendProgramsHandler,
+		Legacy:      legacyRecommendProgramsHandler,
+		OperationID: "recommendPrograms",
+		Summary:     "依修課紀錄推薦完成度最高的學程",
+		Form:        []formField{studentJSONField, formatField(recommendFormats)},
+		Response:    []engine.Recommendation{},
+		Formats:     recommendFormats,
+		Errors:      []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity},
+	},
+}
+
+// 註冊 API 路由：正式版本位於 /api/v1，舊路徑 /api 保留作為相容別名
+func registerAPIRoutes(r *mux.Router) {
+	for _, prefix := range []string{"/api/v1", "/api"} {
+		for _, route := range apiRoutes {
+			methods := []string{route.Method}
+			if route.Method == "POST" {
+				methods = append(methods, "OPTIONS")
+			}
+			handler := route.Handler
+			if prefix == "/api" && route.Legacy != nil {
+				handler = route.Legacy
+			}
+			r.HandleFunc(prefix+route.Path, handler).Methods(methods...)
+		}
+	}
+	r.HandleFunc("/api/openapi.json", openAPIHandler).Methods("GET")
+}
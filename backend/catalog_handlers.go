@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"internal.company/NCCU-Pro/engine"
+)
+
+// --- 學程、課程與系所查詢的 HTTP 處理函式 ---
+
+// 學程列表與搜尋
+func listProgramsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	writeJSON(w, http.StatusOK, requestChecker(r).Catalog().Search(engine.ProgramQuery{
+		Text:    query.Get("q"),
+		Type:    query.Get("type"),
+		College: query.Get("college"),
+		Course:  query.Get("course"),
+	}))
+}
+
+// 單一學程詳細資料
+func getProgramHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	detail, ok := requestChecker(r).Catalog().Detail(id)
+	if !ok {
+		writeError(w, newAPIError(http.StatusNotFound, errCodeProgramNotFound, fmt.Sprintf("學程 ID %s 不存在", id), "id"))
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// 處理課程反查 (每門已通過課程可認列於哪些學程)
+func courseContributionsHandler(w http.ResponseWriter, r *http.Request) {
+	studentCourses, _, apiErr := parseStudentDataFromRequest(w, r)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, requestChecker(r).Catalog().CourseContributions(studentCourses))
+}
+
+// 系所列表；指定 name 時回傳解析後的單一系所
+func listDepartmentsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	writeJSON(w, http.StatusOK, requestChecker(r).Departments().Search(engine.DepartmentQuery{
+		College: query.Get("college"),
+		Name:    query.Get("name"),
+	}))
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"internal.company/NCCU-Pro/engine"
+)
+
+// --- 命令列模式 (離線檢核與批次腳本使用) ---
+
+const cliUsage = `用法: program-checker <子命令> [參數]
+
+子命令:
+  serve          啟動 HTTP 伺服器 (未指定子命令或第一個參數為旗標時的預設行為；--config 設定檔)
+  check          檢核指定學程   (--transcript 成績檔 --program 學程ID[,學程ID...])
+  recommend      推薦完成度最高的學程 (--transcript 成績檔)
+  list-programs  列出學程 (可用 --q、--type、--college、--course 篩選)
+  cohort         批次統計一個目錄下所有成績檔在各學程的完成情形 (--dir 目錄)
+
+共用參數:
+  --data     學程與系所資料目錄 (預設 data)
+  --format   輸出格式：table 或 json (預設 table)
+
+執行 "program-checker <子命令> -h" 查看各子命令的參數說明。
+`
+
+// 命令列參數錯誤 (以結束代碼 2 表示)
+var errUsage = errors.New("參數錯誤")
+
+// runCLI 執行子命令並回傳結束代碼
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	cmd, rest := args[0], args[1:]
+	// 未指定子命令而直接帶參數 (例如 --config、--data) 時視為 serve 的參數
+	if strings.HasPrefix(cmd, "-") && cmd != "-h" && cmd != "--help" {
+		cmd, rest = "serve", args
+	}
+
+	var err error
+	switch cmd {
+	case "serve":
+		err = runServe(rest, stderr)
+	case "check":
+		err = runCheck(rest, stdout, stderr)
+	case "recommend":
+		err = runRecommend(rest, stdout, stderr)
+	case "list-programs":
+		err = runListPrograms(rest, stdout, stderr)
+	case "cohort":
+		err = runCohort(rest, stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, cliUsage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "未知的子命令: %s\n\n%s", cmd, cliUsage)
+		return 2
+	}
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(stderr, err)
+		return 2
+	default:
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+}
+
+// 各子命令共用的參數
+type commonFlags struct {
+	data   string // 學程與系所資料目錄
+	format string // table 或 json
+}
+
+// 建立子命令的參數解析器 (含共用參數)
+func newFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	common := &commonFlags{}
+	fs.StringVar(&common.data, "data", defaultDataDir, "學程與系所資料目錄")
+	fs.StringVar(&common.format, "format", "table", "輸出格式：table 或 json")
+	return fs, common
+}
+
+// 解析參數；無法解析的參數視為參數錯誤 (-h 仍回傳 flag.ErrHelp)
+func parseArgs(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return err
+}
+
+// 解析參數並檢查輸出格式
+func parseFlags(fs *flag.FlagSet, args []string, common *commonFlags) error {
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if common.format != "table" && common.format != "json" {
+		return fmt.Errorf("%w: 不支援的輸出格式 %q", errUsage, common.format)
+	}
+	return nil
+}
+
+// 讀取並合併成績檔 (多個檔案以逗號分隔)，解析警告與成績衝突輸出至 stderr
+func readTranscript(checker *engine.Checker, paths string, stderr io.Writer) ([]engine.StudentCourse, engine.StudentProfile, error) {
+	if paths == "" {
+		return nil, engine.StudentProfile{}, fmt.Errorf("%w: 請以 --transcript 指定成績檔", errUsage)
+	}
+	var files []engine.TranscriptFile
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, engine.StudentProfile{}, fmt.Errorf("讀取檔案失敗: %w", err)
+		}
+		files = append(files, engine.TranscriptFile{Name: path, Data: data})
+	}
+	transcript, err := checker.MergeTranscripts(files)
+	if err != nil {
+		if len(files) == 1 {
+			return nil, engine.StudentProfile{}, fmt.Errorf("%s: %w", files[0].Name, err)
+		}
+		return nil, engine.StudentProfile{}, err
+	}
+	for _, d := range transcript.Diagnostics {
+		fmt.Fprintf(stderr, "警告: %s 第 %d 筆: %s\n", d.File, d.Row, d.Message)
+	}
+	for _, c := range transcript.Conflicts {
+		fmt.Fprintf(stderr, "警告: %s (%s) 的成績不一致：%s，採用 %s 的紀錄\n",
+			c.Name, c.Semester, strings.Join(c.Scores, " / "), c.Files[0])
+	}
+	return transcript.Courses, transcript.Profile, nil
+}
+
+func writeJSONOutput(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func runServe(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", os.Getenv("CONFIG_FILE"), "設定檔 (JSON)，預設讀取環境變數 CONFIG_FILE")
+	data := fs.String("data", "", "學程與系所資料目錄 (覆寫設定檔與環境變數 DATA_DIR)")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	cfg, err := loadConfig(*configPath, os.Getenv)
+	if err != nil {
+		return err
+	}
+	if *data != "" {
+		cfg.DataDir = *data
+	}
+	return serve(cfg)
+}
+
+func runCheck(args []string, stdout, stderr io.Writer) error {
+	fs, common := newFlagSet("check", stderr)
+	transcript := fs.String("transcript", "", "成績檔 (全人系統匯出的 JSON、課程列表 JSON 或 CSV)，多個檔案以逗號分隔並合併")
+	programList := fs.String("program", "", "學程 ID，多個以逗號分隔")
+	if err := parseFlags(fs, args, common); err != nil {
+		return err
+	}
+	if *programList == "" {
+		return fmt.Errorf("%w: 請以 --program 指定學程 ID", errUsage)
+	}
+
+	checker, err := engine.Load(common.data)
+	if err != nil {
+		return err
+	}
+	courses, profile, err := readTranscript(checker, *transcript, stderr)
+	if err != nil {
+		return err
+	}
+
+	var results []engine.CheckResult
+	for _, id := range strings.Split(*programList, ",") {
+		result, err := checker.Check(strings.TrimSpace(id), courses, profile)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+
+	if common.format == "json" {
+		return writeJSONOutput(stdout, results)
+	}
+	for i, result := range results {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		printCheckResult(stdout, result)
+	}
+	return nil
+}
+
+func runRecommend(args []string, stdout, stderr io.Writer) error {
+	fs, common := newFlagSet("recommend", stderr)
+	transcript := fs.String("transcript", "", "成績檔 (全人系統匯出的 JSON、課程列表 JSON 或 CSV)，多個檔案以逗號分隔並合併")
+	if err := parseFlags(fs, args, common); err != nil {
+		return err
+	}
+
+	checker, err := engine.Load(common.data)
+	if err != nil {
+		return err
+	}
+	courses, profile, err := readTranscript(checker, *transcript, stderr)
+	if err != nil {
+		return err
+	}
+
+	recommendations := checker.Recommend(courses, profile)
+	if common.format == "json" {
+		return writeJSONOutput(stdout, recommendations)
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "完成度\t學程 ID\t學程名稱\t已修/應修學分\t狀態")
+	for _, rec := range recommendations {
+		status := "未完成"
+		if rec.IsCompleted {
+			status = "已完成"
+		}
+		if rec.IsRestricted {
+			status += " (資格限制)"
+		}
+		fmt.Fprintf(tw, "%.0f%%\t%s\t%s\t%.1f/%.1f\t%s\n", rec.CompletionRate*100, rec.ProgramID, rec.ProgramName, rec.TotalPassedCredits, rec.MinCredits, status)
+	}
+	return tw.Flush()
+}
+
+func runListPrograms(args []string, stdout, stderr io.Writer) error {
+	fs, common := newFlagSet("list-programs", stderr)
+	var q engine.ProgramQuery
+	fs.StringVar(&q.Text, "q", "", "名稱或說明的關鍵字")
+	fs.StringVar(&q.Type, "type", "", "學程類型：micro、credit 或 specialty")
+	fs.StringVar(&q.College, "college", "", "所屬學院")
+	fs.StringVar(&q.Course, "course", "", "包含此課程的學程")
+	if err := parseFlags(fs, args, common); err != nil {
+		return err
+	}
+
+	checker, err := engine.Load(common.data)
+	if err != nil {
+		return err
+	}
+
+	list := checker.Catalog().Search(q)
+	if common.format == "json" {
+		return writeJSONOutput(stdout, list)
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "學程 ID\t類型\t最低學分\t學程名稱\t學院")
+	for _, p := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%.1f\t%s\t%s\n", p.ID, p.Type, p.MinCredits, p.Name, strings.Join(p.Colleges, "、"))
+	}
+	return tw.Flush()
+}
+
+// 以表格形式輸出單一學程的檢核結果
+func printCheckResult(w io.Writer, result engine.CheckResult) {
+	status := "未完成"
+	if result.IsCompleted {
+		status = "已完成"
+	}
+	fmt.Fprintf(w, "%s：%s\n", result.ProgramName, status)
+	fmt.Fprintf(w, "總學分：%s / %s\n", result.TotalPassedCredits, result.MinRequiredCredits)
+	if result.AvgScoreRequired {
+		fmt.Fprintf(w, "平均成績：%s (門檻 %s)\n", result.AvgScore, result.AvgScoreThreshold)
+	}
+	if result.RestrictionMessage != "" {
+		fmt.Fprintf(w, "資格限制：%s\n", result.RestrictionMessage)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "分類\t門數\t學分\t達成\t已修課程")
+	for _, cat := range result.CategoryResults {
+		met := "否"
+		if cat.IsMet {
+			met = "是"
+		}
+		var names []string
+		for _, c := range cat.PassedCourses {
+			names = append(names, c.Name)
+		}
+		fmt.Fprintf(tw, "%s\t%d/%d\t%.1f/%.1f\t%s\t%s\n", cat.Category, cat.PassedCount, cat.RequiredCount, cat.PassedCredits, cat.RequiredCredits, met, strings.Join(names, "、"))
+		if cat.LimitExceeded && cat.ExceededMessage != "" {
+			fmt.Fprintf(tw, "\t\t\t\t※ %s\n", cat.ExceededMessage)
+		}
+	}
+	tw.Flush()
+
+	if len(result.InProgressCourses) > 0 {
+		var names []string
+		for _, c := range result.InProgressCourses {
+			names = append(names, fmt.Sprintf("%s (%s)", c.Name, c.Semester))
+		}
+		fmt.Fprintf(w, "修習中：%s\n", strings.Join(names, "、"))
+	}
+}
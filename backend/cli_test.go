@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"internal.company/NCCU-Pro/engine"
+)
+
+func TestRunCLI(t *testing.T) {
+	const sample = "testdata/transcript_sample.json"
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout []string // stdout 須包含的字串
+		wantStderr string
+	}{
+		{"說明", []string{"help"}, 0, []string{"子命令:"}, ""},
+		{"子命令說明", []string{"check", "-h"}, 0, nil, "-program"},
+		{"檢核表格", []string{"check", "--transcript", sample, "--program", "CFA,fintech"}, 0,
+			[]string{"CFA核心學程：未完成", "總學分：15.0 / 21.0", "平均成績：86.50 (門檻 80)", "財務管理、投資學", "金融科技專長學程：未完成"}, ""},
+		{"推薦表格", []string{"recommend", "--transcript", sample}, 0, []string{"完成度", "CFA", "15.0/21.0"}, ""},
+		{"列出學程", []string{"list-programs", "--course", "投資學"}, 0, []string{"學程 ID", "CFA", "specialty"}, ""},
+		{"未知的子命令", []string{"grade"}, 2, nil, "未知的子命令: grade"},
+		{"未指定子命令時的參數", []string{"--config", "testdata/missing-config.json"}, 1, nil, "missing-config.json"},
+		{"未指定子命令時的未知參數", []string{"--program", "CFA"}, 2, nil, "-config"},
+		{"缺少學程", []string{"check", "--transcript", sample}, 2, nil, "--program"},
+		{"缺少成績檔", []string{"recommend"}, 2, nil, "--transcript"},
+		{"不支援的格式", []string{"list-programs", "--format", "xml"}, 2, nil, "xml"},
+		{"未知的參數", []string{"check", "--programs", "CFA"}, 2, nil, "programs"},
+		{"學程不存在", []string{"check", "--transcript", sample, "--program", "nope"}, 1, nil, engine.ErrProgramNotFound.Error()},
+		{"成績檔不存在", []string{"recommend", "--transcript", "testdata/missing.json"}, 1, nil, "讀取檔案失敗"},
+		{"資料目錄不存在", []string{"list-programs", "--data", t.TempDir()}, 1, nil, "初始化失敗"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runCLI(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Fatalf("結束代碼 %d，預期 %d\nstderr: %s", code, tt.wantCode, stderr.String())
+			}
+			for _, want := range tt.wantStdout {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("stdout 缺少 %q:\n%s", want, stdout.String())
+				}
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr 缺少 %q:\n%s", tt.wantStderr, stderr.String())
+			}
+		})
+	}
+}
+
+// --format json 的輸出須可直接解析為 API 相同的結構 (腳本以此比對檢核結果)
+func TestRunCLIJSON(t *testing.T) {
+	run := func(t *testing.T, v any, args ...string) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		if code := runCLI(append(args, "--format", "json"), &stdout, &stderr); code != 0 {
+			t.Fatalf("結束代碼 %d: %s", code, stderr.String())
+		}
+		if err := json.Unmarshal(stdout.Bytes(), v); err != nil {
+			t.Fatalf("輸出不是有效的 JSON: %v\n%s", err, stdout.String())
+		}
+	}
+
+	var results []engine.CheckResult
+	run(t, &results, "check", "--transcript", "testdata/transcript_sample.json", "--program", "CFA,fintech")
+	if len(results) != 2 || results[0].ProgramName != "CFA核心學程" || results[0].TotalPassedCredits != "15.0" || results[1].IsCompleted {
+		t.Errorf("檢核結果 %+v", results)
+	}
+
+	var recommendations []engine.Recommendation
+	run(t, &recommendations, "recommend", "--transcript", "testdata/transcript_sample.json")
+	if len(recommendations) == 0 {
+		t.Fatal("沒有推薦結果")
+	}
+	for i := 1; i < len(recommendations); i++ {
+		if recommendations[i].CompletionRate > recommendations[i-1].CompletionRate {
+			t.Errorf("推薦結果未依完成度排序: %+v", recommendations)
+		}
+	}
+
+	var programs []engine.ProgramSummary
+	run(t, &programs, "list-programs", "--type", "specialty", "--course", "投資學")
+	if len(programs) == 0 {
+		t.Fatal("沒有符合的學程")
+	}
+	for _, p := range programs {
+		if p.Type != "specialty" {
+			t.Errorf("學程 %s 的類型 %s", p.ID, p.Type)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"internal.company/NCCU-Pro/engine"
+)
+
+// --- 批次分析 (統計一批成績檔在各學程的完成情形，僅輸出彙總數據) ---
+
+// 單一學程的彙總統計
+type ProgramCohortStats struct {
+	ProgramID              string `json:"programID"`
+	ProgramName            string `json:"programName"`
+	Students               int    `json:"students"`
+	Completed              int    `json:"completed"`
+	AtLeast50Percent       int    `json:"atLeast50Percent"`
+	AtLeast80Percent       int    `json:"atLeast80Percent"`
+	MostCommonMissing      string `json:"mostCommonMissing"`      // 最常未達成的分類
+	MostCommonMissingCount int    `json:"mostCommonMissingCount"` // 未達成該分類的人數
+}
+
+// 批次分析結果
+type CohortReport struct {
+	Transcripts int                  `json:"transcripts"`
+	Parsed      int                  `json:"parsed"`
+	Failed      int                  `json:"failed"`
+	Programs    []ProgramCohortStats `json:"programs"`
+}
+
+// 單一學生在單一學程的檢核摘要 (不含任何課程或個人資料)
+type programOutcome struct {
+	programID string
+	completed bool
+	rate      float64
+	missing   []string
+}
+
+// 單一成績檔的處理結果
+type transcriptOutcome struct {
+	path     string
+	err      error
+	programs []programOutcome
+}
+
+// 以固定數量的 worker 並行處理所有成績檔 (同時執行的 check 不超過 workers 個)，處理結果完成即送出
+func cohortWorkers(paths []string, workers int, check func(path string) transcriptOutcome) <-chan transcriptOutcome {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	outcomes := make(chan transcriptOutcome)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				outcomes <- check(path)
+			}
+		}()
+	}
+	go func() {
+		for _, path := range paths {
+			jobs <- path
+		}
+		close(jobs)
+	}()
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+	return outcomes
+}
+
+// 並行檢核所有成績檔，並彙總各學程的統計數據；無法處理的檔案輸出至 warn 並計入 Failed
+func analyzeCohort(checker *engine.Checker, paths []string, programIDs []string, workers int, warn io.Writer) CohortReport {
+	outcomes := cohortWorkers(paths, workers, func(path string) transcriptOutcome {
+		return checkTranscriptFile(checker, path, programIDs)
+	})
+
+	report := CohortReport{Transcripts: len(paths)}
+	stats := make(map[string]*ProgramCohortStats)
+	missingCounts := make(map[string]map[string]int)
+	for _, id := range programIDs {
+		stats[id] = &ProgramCohortStats{ProgramID: id, ProgramName: checker.Catalog().Summary(id).Name}
+		missingCounts[id] = make(map[string]int)
+	}
+
+	for outcome := range outcomes {
+		if outcome.err != nil {
+			report.Failed++
+			fmt.Fprintf(warn, "略過 %s: %v\n", outcome.path, outcome.err)
+			continue
+		}
+		report.Parsed++
+		for _, po := range outcome.programs {
+			s := stats[po.programID]
+			s.Students++
+			if po.completed {
+				s.Completed++
+			}
+			if po.rate >= 0.5 {
+				s.AtLeast50Percent++
+			}
+			if po.rate >= 0.8 {
+				s.AtLeast80Percent++
+			}
+			for _, category := range po.missing {
+				missingCounts[po.programID][category]++
+			}
+		}
+	}
+
+	for _, id := range programIDs {
+		s := stats[id]
+		for category, count := range missingCounts[id] {
+			// 人數相同時取分類名稱較小者，確保輸出穩定
+			if count > s.MostCommonMissingCount || (count == s.MostCommonMissingCount && category < s.MostCommonMissing) {
+				s.MostCommonMissing = category
+				s.MostCommonMissingCount = count
+			}
+		}
+		report.Programs = append(report.Programs, *s)
+	}
+	return report
+}
+
+// 檢核單一成績檔，僅保留各學程的完成度摘要
+func checkTranscriptFile(checker *engine.Checker, path string, programIDs []string) transcriptOutcome {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return transcriptOutcome{path: path, err: err}
+	}
+	transcript, err := checker.ParseTranscript(data)
+	if err != nil {
+		return transcriptOutcome{path: path, err: err}
+	}
+	courses, profile := transcript.Courses, transcript.Profile
+
+	outcome := transcriptOutcome{path: path}
+	for _, id := range programIDs {
+		result, err := checker.Check(id, courses, profile)
+		if err != nil {
+			return transcriptOutcome{path: path, err: err}
+		}
+		rate, _, _, _ := engine.CompletionRate(result)
+
+		var missing []string
+		for _, cat := range result.CategoryResults {
+			if !cat.IsMet {
+				missing = append(missing, cat.Category)
+			}
+		}
+		outcome.programs = append(outcome.programs, programOutcome{
+			programID: id,
+			completed: result.IsCompleted,
+			rate:      rate,
+			missing:   missing,
+		})
+	}
+	return outcome
+}
+
+// 列出目錄下 (含子目錄) 所有的成績檔 (.json 與 .csv，副檔名不分大小寫)；結果依路徑排序
+func findTranscripts(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !d.IsDir() && (ext == ".json" || ext == ".csv") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// 以 CSV 格式輸出批次分析結果
+func writeCohortCSV(w io.Writer, report CohortReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"program_id", "program_name", "students", "completed", "at_least_50_percent", "at_least_80_percent", "most_common_missing_category", "most_common_missing_count"})
+	for _, s := range report.Programs {
+		cw.Write([]string{
+			s.ProgramID,
+			s.ProgramName,
+			strconv.Itoa(s.Students),
+			strconv.Itoa(s.Completed),
+			strconv.Itoa(s.AtLeast50Percent),
+			strconv.Itoa(s.AtLeast80Percent),
+			s.MostCommonMissing,
+			strconv.Itoa(s.MostCommonMissingCount),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func runCohort(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("cohort", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	data := flags.String("data", defaultDataDir, "學程與系所資料目錄")
+	dir := flags.String("dir", "", "成績檔所在目錄 (含子目錄中的 .json 與 .csv 檔)")
+	programList := flags.String("program", "", "僅統計指定學程，多個以逗號分隔 (預設為全部學程)")
+	workers := flags.Int("workers", 4, "並行處理的 worker 數量")
+	format := flags.String("format", "csv", "輸出格式：csv 或 json")
+	if err := parseArgs(flags, args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return fmt.Errorf("%w: 請以 --dir 指定成績檔目錄", errUsage)
+	}
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("%w: 不支援的輸出格式 %q", errUsage, *format)
+	}
+
+	checker, err := engine.Load(*data)
+	if err != nil {
+		return err
+	}
+
+	var programIDs []string
+	if *programList != "" {
+		for _, id := range strings.Split(*programList, ",") {
+			id = strings.TrimSpace(id)
+			if !checker.Catalog().Has(id) {
+				return fmt.Errorf("%w: %s", engine.ErrProgramNotFound, id)
+			}
+			programIDs = append(programIDs, id)
+		}
+	} else {
+		programIDs = checker.Catalog().IDs()
+	}
+
+	paths, err := findTranscripts(*dir)
+	if err != nil {
+		return fmt.Errorf("讀取目錄失敗: %w", err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("%s 中沒有任何 .json 或 .csv 成績檔", *dir)
+	}
+
+	report := analyzeCohort(checker, paths, programIDs, *workers, stderr)
+	fmt.Fprintf(stderr, "共 %d 份成績檔，成功解析 %d 份，失敗 %d 份\n", report.Transcripts, report.Parsed, report.Failed)
+
+	if *format == "json" {
+		return writeJSONOutput(stdout, report)
+	}
+	return writeCohortCSV(stdout, report)
+}
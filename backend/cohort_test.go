@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"internal.company/NCCU-Pro/engine"
+)
+
+// 建立含有效與無效成績檔的目錄，回傳目錄與有效成績檔的內容
+func writeCohortDir(t *testing.T) (string, [][]byte) {
+	t.Helper()
+	sample, err := os.ReadFile("testdata/transcript_sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvData := []byte("課程名稱,學分,成績,學年,學期,主修\n財務管理,3,91,112,1,財務管理學系\n投資學,3,72,112,1,財務管理學系\n計算機概論,3,90,111,1,財務管理學系\n")
+	canonical := []byte(`{"major": "資訊管理學系", "courses": [{"name": "程式設計一", "credit": 3, "score": 88, "year": "111", "semester": 1}]}`)
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"a.json":         sample,
+		"b.CSV":          csvData,
+		"sub/c.json":     canonical,
+		"broken.json":    []byte("{not json"),
+		"empty.csv":      []byte("課程名稱,學分,成績\n"),
+		"notes.txt":      []byte("不是成績檔"),
+		"sub/readme.md":  []byte("# 說明"),
+		"sub/deep/d.txt": sample,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, [][]byte{sample, csvData, canonical}
+}
+
+func TestFindTranscripts(t *testing.T) {
+	dir, _ := writeCohortDir(t)
+	paths, err := findTranscripts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, path := range paths {
+		rel, _ := filepath.Rel(dir, path)
+		names = append(names, filepath.ToSlash(rel))
+	}
+	want := []string{"a.json", "b.CSV", "broken.json", "empty.csv", "sub/c.json"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("找到的成績檔 %v，預期 %v", names, want)
+	}
+}
+
+func TestAnalyzeCohort(t *testing.T) {
+	dir, valid := writeCohortDir(t)
+	paths, err := findTranscripts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{"CFA", "fintech", "patent"}
+
+	var warn bytes.Buffer
+	report := analyzeCohort(testChecker, paths, ids, 3, &warn)
+	if report.Transcripts != 5 || report.Parsed != 3 || report.Failed != 2 {
+		t.Errorf("檔案統計 %d / %d / %d", report.Transcripts, report.Parsed, report.Failed)
+	}
+	// 無法解析的檔案逐一列出，不中斷其他檔案的統計
+	for _, name := range []string{"broken.json", "empty.csv"} {
+		if !strings.Contains(warn.String(), "略過 "+filepath.Join(dir, name)) {
+			t.Errorf("警告缺少 %s:\n%s", name, warn.String())
+		}
+	}
+
+	// 與逐一檢核的結果比對
+	for i, id := range ids {
+		stats := report.Programs[i]
+		if stats.ProgramID != id || stats.ProgramName != testChecker.Catalog().Summary(id).Name {
+			t.Errorf("第 %d 個學程為 %s (%s)", i, stats.ProgramID, stats.ProgramName)
+		}
+		want := ProgramCohortStats{ProgramID: id, ProgramName: stats.ProgramName}
+		missing := make(map[string]int)
+		for _, data := range valid {
+			transcript, err := testChecker.ParseTranscript(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, _ := testChecker.Check(id, transcript.Courses, transcript.Profile)
+			rate, _, _, _ := engine.CompletionRate(result)
+			want.Students++
+			if result.IsCompleted {
+				want.Completed++
+			}
+			if rate >= 0.5 {
+				want.AtLeast50Percent++
+			}
+			if rate >= 0.8 {
+				want.AtLeast80Percent++
+			}
+			for _, cat := range result.CategoryResults {
+				if !cat.IsMet {
+					missing[cat.Category]++
+				}
+			}
+		}
+		for _, count := range missing {
+			want.MostCommonMissingCount = max(want.MostCommonMissingCount, count)
+		}
+		// 人數相同的分類取名稱最小者
+		for category, count := range missing {
+			if count == want.MostCommonMissingCount && (want.MostCommonMissing == "" || category < want.MostCommonMissing) {
+				want.MostCommonMissing = category
+			}
+		}
+		if stats != want {
+			t.Errorf("%s 的統計 %+v，預期 %+v", id, stats, want)
+		}
+	}
+
+	// 輸出與 worker 數量無關
+	for _, workers := range []int{0, 1, 8} {
+		if got := analyzeCohort(testChecker, paths, ids, workers, &bytes.Buffer{}); !reflect.DeepEqual(got, report) {
+			t.Errorf("workers=%d 的結果不同: %+v", workers, got)
+		}
+	}
+}
+
+// 同時處理的成績檔不超過 worker 數量，且每個檔案恰好處理一次
+func TestCohortWorkersBound(t *testing.T) {
+	paths := make([]string, 40)
+	for i := range paths {
+		paths[i] = filepath.Join("dir", string(rune('a'+i%26)), strings.Repeat("x", i))
+	}
+
+	const workers = 3
+	var mu sync.Mutex
+	running, peak := 0, 0
+	seen := make(map[string]int)
+	outcomes := cohortWorkers(paths, workers, func(path string) transcriptOutcome {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		seen[path]++
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return transcriptOutcome{path: path}
+	})
+
+	count := 0
+	for range outcomes {
+		count++
+	}
+	if count != len(paths) || len(seen) != len(paths) {
+		t.Errorf("處理了 %d 個結果、%d 個不同的檔案，預期 %d", count, len(seen), len(paths))
+	}
+	if peak > workers || peak < 2 {
+		t.Errorf("同時處理的檔案最多 %d 個，預期介於 2 與 %d 之間", peak, workers)
+	}
+}
+
+// 命令列的 cohort 子命令：JSON 輸出只含彙總數據
+func TestRunCohort(t *testing.T) {
+	dir, _ := writeCohortDir(t)
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"cohort", "--dir", dir, "--program", "CFA", "--format", "json"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("結束代碼 %d: %s", code, stderr.String())
+	}
+	var report CohortReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Parsed != 3 || report.Failed != 2 || len(report.Programs) != 1 {
+		t.Errorf("批次分析結果 %+v", report)
+	}
+	if !strings.Contains(stderr.String(), "成功解析 3 份，失敗 2 份") {
+		t.Errorf("stderr: %s", stderr.String())
+	}
+	if strings.Contains(stdout.String(), "財務管理") {
+		t.Errorf("輸出不應包含課程名稱: %s", stdout.String())
+	}
+
+	if code := runCLI([]string{"cohort", "--dir", t.TempDir()}, &bytes.Buffer{}, &stderr); code != 1 {
+		t.Errorf("空目錄的結束代碼 %d", code)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- 伺服器設定 ---
+//
+// 優先順序：命令列參數 > 環境變數 > 設定檔 (JSON) > 預設值。
+
+// 伺服器設定 (設定檔的欄位名稱與 JSON 標籤相同)
+type serverConfig struct {
+	Port           string   `json:"port"`
+	AllowedOrigins []string `json:"allowedOrigins"` // CORS 允許的來源；含 "*" 時允許所有來源
+	MaxUploadMB    int64    `json:"maxUploadMB"`    // 單一請求 (含所有成績檔) 的大小上限
+	DataDir        string   `json:"dataDir"`
+	StaticDir      string   `json:"staticDir"` // 前端靜態檔案目錄
+	LogFormat      string   `json:"logFormat"` // text 或 json
+	// PDF 報表嵌入的 TrueType 字型檔 (.ttf)；未設定時使用 <dataDir>/fonts/report.ttf，兩者皆無時停用 PDF 報表
+	PDFFont string `json:"pdfFont"`
+
+	// 速率與並行限制：鍵為路由樣板 (例如 /api/v1/recommend) 或 default；設定檔中的項目會覆寫同名的預設值
+	RateLimits map[string]rateLimit `json:"rateLimits"`
+	// 反向代理帶入用戶端 IP 的標頭 (例如 X-Forwarded-For)；僅在伺服器位於受信任的代理之後時設定
+	TrustedProxyHeader string `json:"trustedProxyHeader"`
+
+	// 檢核結果快取 (僅存於記憶體)：最多保留的學程結果數與保留時間；任一為 0 時停用
+	ResultCacheSize int      `json:"resultCacheSize"`
+	ResultCacheTTL  duration `json:"resultCacheTTL"`
+
+	ReadHeaderTimeout duration `json:"readHeaderTimeout"`
+	ReadTimeout       duration `json:"readTimeout"`
+	WriteTimeout      duration `json:"writeTimeout"`
+	IdleTimeout       duration `json:"idleTimeout"`
+	ShutdownTimeout   duration `json:"shutdownTimeout"` // 收到 SIGTERM 後等待進行中請求完成的時間
+}
+
+// 設定檔中的時間長度：字串 (例如 "30s"、"2m") 或秒數
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var seconds float64
+		if err := json.Unmarshal(data, &seconds); err != nil {
+			return fmt.Errorf("時間長度須為字串 (例如 \"30s\") 或秒數: %s", data)
+		}
+		*d = duration(seconds * float64(time.Second))
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("無效的時間長度 %q", s)
+	}
+	*d = duration(v)
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func defaultConfig() serverConfig {
+	return serverConfig{
+		Port:              "8080",
+		AllowedOrigins:    []string{"*"},
+		MaxUploadMB:       32,
+		DataDir:           defaultDataDir,
+		StaticDir:         "../frontend", // 假設 backend 與 frontend 為同級目錄
+		LogFormat:         "text",
+		RateLimits:        defaultRateLimits(),
+		ResultCacheSize:   1000,
+		ResultCacheTTL:    duration(5 * time.Minute),
+		ReadHeaderTimeout: duration(10 * time.Second),
+		ReadTimeout:       duration(30 * time.Second),
+		WriteTimeout:      duration(60 * time.Second), // 產生 PDF 報告需要較長時間
+		IdleTimeout:       duration(120 * time.Second),
+		ShutdownTimeout:   duration(15 * time.Second),
+	}
+}
+
+// 上傳大小上限 (位元組)
+func (c serverConfig) maxUploadBytes() int64 {
+	return c.MaxUploadMB << 20
+}
+
+// 讀取設定：path 非空時先讀取設定檔，再以 getenv 取得的環境變數覆寫
+func loadConfig(path string, getenv func(string) string) (serverConfig, error) {
+	cfg := defaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("讀取設定檔失敗: %w", err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields() // 欄位名稱打錯時直接報錯，而非默默使用預設值
+		if err := dec.Decode(&cfg); err != nil {
+			return cfg, fmt.Errorf("解析設定檔 %s 失敗: %w", path, err)
+		}
+	}
+	if err := applyEnv(&cfg, getenv); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// 以環境變數覆寫設定
+func applyEnv(cfg *serverConfig, getenv func(string) string) error {
+	for name, dst := range map[string]*string{
+		"PORT":                 &cfg.Port,
+		"DATA_DIR":             &cfg.DataDir,
+		"STATIC_DIR":           &cfg.StaticDir,
+		"LOG_FORMAT":           &cfg.LogFormat,
+		"PDF_FONT_PATH":        &cfg.PDFFont,
+		"TRUSTED_PROXY_HEADER": &cfg.TrustedProxyHeader,
+	} {
+		if v := getenv(name); v != "" {
+			*dst = v
+		}
+	}
+	if v := getenv("ALLOWED_ORIGINS"); v != "" {
+		cfg.AllowedOrigins = nil
+		for _, origin := range strings.Split(v, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				cfg.AllowedOrigins = append(cfg.AllowedOrigins, origin)
+			}
+		}
+	}
+	if v := getenv("MAX_UPLOAD_MB"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("環境變數 MAX_UPLOAD_MB 須為整數: %q", v)
+		}
+		cfg.MaxUploadMB = n
+	}
+	if v := getenv("RESULT_CACHE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("環境變數 RESULT_CACHE_SIZE 須為整數: %q", v)
+		}
+		cfg.ResultCacheSize = n
+	}
+	for name, dst := range map[string]*duration{
+		"READ_HEADER_TIMEOUT": &cfg.ReadHeaderTimeout,
+		"READ_TIMEOUT":        &cfg.ReadTimeout,
+		"WRITE_TIMEOUT":       &cfg.WriteTimeout,
+		"IDLE_TIMEOUT":        &cfg.IdleTimeout,
+		"SHUTDOWN_TIMEOUT":    &cfg.ShutdownTimeout,
+		"RESULT_CACHE_TTL":    &cfg.ResultCacheTTL,
+	} {
+		if v := getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("環境變數 %s 須為時間長度 (例如 30s): %q", name, v)
+			}
+			*dst = duration(d)
+		}
+	}
+	return nil
+}
+
+func (c serverConfig) validate() error {
+	if c.Port == "" {
+		return fmt.Errorf("設定錯誤: 未指定 port")
+	}
+	if len(c.AllowedOrigins) == 0 {
+		return fmt.Errorf("設定錯誤: allowedOrigins 不可為空 (允許所有來源請設為 \"*\")")
+	}
+	if c.MaxUploadMB <= 0 {
+		return fmt.Errorf("設定錯誤: maxUploadMB 須大於 0")
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("設定錯誤: 不支援的 logFormat %q (可用 text 或 json)", c.LogFormat)
+	}
+	if c.ResultCacheSize < 0 {
+		return fmt.Errorf("設定錯誤: resultCacheSize 不可為負數")
+	}
+	if _, ok := c.RateLimits[defaultRateLimitKey]; !ok {
+		return fmt.Errorf("設定錯誤: rateLimits 缺少 %s", defaultRateLimitKey)
+	}
+	for route, limit := range c.RateLimits {
+		if route != defaultRateLimitKey && !strings.HasPrefix(route, "/") {
+			return fmt.Errorf("設定錯誤: rateLimits 的鍵須為路由樣板或 %s: %q", defaultRateLimitKey, route)
+		}
+		if limit.RequestsPerMinute < 0 || limit.Burst < 0 || limit.MaxConcurrent < 0 {
+			return fmt.Errorf("設定錯誤: rateLimits[%q] 不可為負數", route)
+		}
+	}
+	for name, d := range map[string]duration{
+		"readHeaderTimeout": c.ReadHeaderTimeout,
+		"readTimeout":       c.ReadTimeout,
+		"writeTimeout":      c.WriteTimeout,
+		"idleTimeout":       c.IdleTimeout,
+		"shutdownTimeout":   c.ShutdownTimeout,
+		"resultCacheTTL":    c.ResultCacheTTL,
+	} {
+		if d < 0 {
+			return fmt.Errorf("設定錯誤: %s 不可為負數", name)
+		}
+	}
+	return nil
+}
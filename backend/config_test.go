@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"port": "9000",
+		"allowedOrigins": ["https://a.example"],
+		"maxUploadMB": 4,
+		"readTimeout": "5s",
+		"writeTimeout": 90
+	}`)
+	env := map[string]string{
+		"PORT":            "9100",
+		"ALLOWED_ORIGINS": "https://b.example, https://c.example",
+		"IDLE_TIMEOUT":    "1m",
+		"PDF_FONT_PATH":   "/fonts/report.ttf",
+	}
+	cfg, err := loadConfig(path, func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 環境變數優先於設定檔，未設定的欄位沿用設定檔或預設值
+	if cfg.Port != "9100" {
+		t.Errorf("port = %s", cfg.Port)
+	}
+	if strings.Join(cfg.AllowedOrigins, " ") != "https://b.example https://c.example" {
+		t.Errorf("allowedOrigins = %v", cfg.AllowedOrigins)
+	}
+	if cfg.maxUploadBytes() != 4<<20 {
+		t.Errorf("maxUploadBytes = %d", cfg.maxUploadBytes())
+	}
+	if time.Duration(cfg.ReadTimeout) != 5*time.Second || time.Duration(cfg.WriteTimeout) != 90*time.Second || time.Duration(cfg.IdleTimeout) != time.Minute {
+		t.Errorf("逾時設定 %v %v %v", cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout)
+	}
+	if cfg.PDFFont != "/fonts/report.ttf" {
+		t.Errorf("pdfFont = %s", cfg.PDFFont)
+	}
+	if cfg.DataDir != "data" || cfg.StaticDir != "../frontend" {
+		t.Errorf("目錄預設值 %s %s", cfg.DataDir, cfg.StaticDir)
+	}
+
+	srv := newHTTPServer(testChecker, cfg, nil)
+	if srv.Addr != ":9100" || srv.ReadTimeout != 5*time.Second || srv.ReadHeaderTimeout != 10*time.Second {
+		t.Errorf("伺服器設定 %s %v %v", srv.Addr, srv.ReadTimeout, srv.ReadHeaderTimeout)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"未知欄位", `{"maxUpload": 4}`, nil, "maxUpload"},
+		{"無效的時間長度", `{"readTimeout": "soon"}`, nil, "無效的時間長度"},
+		{"上傳上限為 0", `{"maxUploadMB": 0}`, nil, "maxUploadMB"},
+		{"空的來源清單", `{"allowedOrigins": []}`, nil, "allowedOrigins"},
+		{"不支援的記錄格式", "", map[string]string{"LOG_FORMAT": "xml"}, "logFormat"},
+		{"環境變數不是整數", "", map[string]string{"MAX_UPLOAD_MB": "ten"}, "MAX_UPLOAD_MB"},
+		{"負的逾時", "", map[string]string{"WRITE_TIMEOUT": "-1s"}, "writeTimeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			if tt.file != "" {
+				path = writeConfigFile(t, tt.file)
+			}
+			getenv := noEnv
+			if tt.env != nil {
+				getenv = func(name string) string { return tt.env[name] }
+			}
+			_, err := loadConfig(path, getenv)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("錯誤 %v，預期包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	handler := commonMiddleware([]string{"https://nccu.example"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for origin, want := range map[string]string{
+		"https://nccu.example": "https://nccu.example",
+		"https://evil.example": "",
+	} {
+		req := httptest.NewRequest("OPTIONS", "/api/v1/check", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("來源 %s: Access-Control-Allow-Origin = %q，預期 %q", origin, got, want)
+		}
+		if rec.Header().Get("Vary") != "Origin" {
+			t.Errorf("來源 %s: 缺少 Vary: Origin", origin)
+		}
+	}
+}
+
+func TestUploadTooLarge(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MaxUploadMB = 1
+	router := newRouter(testChecker, cfg, nil)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("student_json", "large.csv")
+	fw.Write(bytes.Repeat([]byte("a"), 2<<20))
+	mw.Close()
+	req := httptest.NewRequest("POST", "/api/v1/transcript", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), errCodeUploadTooLarge) {
+		t.Errorf("狀態碼 %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// README 引用的範例設定檔須可直接使用
+func TestExampleConfig(t *testing.T) {
+	cfg, err := loadConfig("config.example.json", func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit := cfg.RateLimits["/api/v1/recommend"]; limit.MaxConcurrent != 2 {
+		t.Errorf("推薦端點的並行上限 %d", limit.MaxConcurrent)
+	}
+	if _, ok := cfg.RateLimits["/api/v1/check"]; !ok {
+		t.Error("設定檔未列出的路由應保留預設值")
+	}
+	if cfg.PDFFont != "data/fonts/report.ttf" {
+		t.Errorf("範例設定應列出 PDF 報表字型的路徑，得到 %q", cfg.PDFFont)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"internal.company/NCCU-Pro/engine"
+)
+
+func TestListDepartments(t *testing.T) {
+	router := newRouter(testChecker, defaultConfig(), nil)
+	get := func(url string) []engine.Department {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: 狀態碼 %d", url, rec.Code)
+		}
+		var list []engine.Department
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+
+	business := get("/api/departments?college=3")
+	if len(business) != 12 {
+		t.Errorf("商學院 %d 個系所，預期 12", len(business))
+	}
+	for _, d := range business {
+		if d.College != "商學院" {
+			t.Errorf("%s 的學院為 %s", d.Name, d.College)
+		}
+	}
+	if got := get("/api/v1/departments?college=商學院"); len(got) != len(business) {
+		t.Errorf("以學院名稱查詢得到 %d 個系所", len(got))
+	}
+
+	got := get("/api/v1/departments?name=" + "保險學系")
+	if len(got) != 1 || got[0].Code != "308" || got[0].DegreeLevel != engine.DegreeBachelor || len(got[0].Aliases) != 1 {
+		t.Errorf("舊名查詢結果 %+v", got)
+	}
+}
+
+// 不同學程目錄的路由各自使用自己的檢核器
+func TestRoutersUseOwnChecker(t *testing.T) {
+	catalog, err := engine.LoadCatalog("engine/testdata/catalog_alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alt := engine.New(catalog, testChecker.Departments())
+	count := func(router http.Handler) int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/programs", nil))
+		var list []engine.ProgramSummary
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		return len(list)
+	}
+	if n := count(newRouter(alt, defaultConfig(), nil)); n != 1 {
+		t.Errorf("測試目錄的學程列表有 %d 個學程，預期 1", n)
+	}
+	if n := count(newRouter(testChecker, defaultConfig(), nil)); n != len(testChecker.Catalog().IDs()) {
+		t.Errorf("data/ 的學程列表有 %d 個學程", n)
+	}
+}
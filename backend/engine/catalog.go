@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// --- 學程目錄 (一個資料目錄中的學程定義、編譯結果與索引；載入後唯讀，可同時載入多份) ---
+
+// 學程定義檔與學程類型的對應 (依固定順序讀取)
+var programFiles = []struct{ filename, pType string }{
+	{"credit_programs.json", "credit"},
+	{"micro_programs.json", "micro"},
+	{"commerce_specialty_programs.json", "specialty"},
+}
+
+// 學程目錄 (例如某一學年度的學程定義)
+type Catalog struct {
+	Dir     string
+	Version string // 定義檔內容的雜湊，內容變更時隨之改變
+
+	programs      map[string]Program            // 學程 ID -> 定義 (跨院學程名稱已去除學院標註)
+	byCollege     map[string]map[string]Program // 依學院分類 (舊版 /api/programs)
+	compiled      map[string]*compiledProgram
+	byCourseName  map[string][]string // 課程名稱 -> 包含此課程的學程 ID (用於一次將學生課程分派至各學程)
+	courseAliases map[string]string   // 課程別名：比對鍵 -> 正式名稱的比對鍵
+	index         *programIndex
+}
+
+// 載入資料目錄中的學程定義與課程別名，並編譯、建立索引
+func LoadCatalog(dir string) (*Catalog, error) {
+	c := &Catalog{
+		Dir:       dir,
+		programs:  make(map[string]Program),
+		byCollege: make(map[string]map[string]Program),
+	}
+	hash := sha256.New()
+
+	aliasFile, err := os.ReadFile(filepath.Join(dir, "course_aliases.json"))
+	if err != nil {
+		return nil, err
+	}
+	if c.courseAliases, err = parseCourseAliases(aliasFile); err != nil {
+		return nil, err
+	}
+	hash.Write(aliasFile)
+
+	var dupErrs []error
+	for _, f := range programFiles {
+		filename, pType := f.filename, f.pType
+		file, err := os.ReadFile(filepath.Join(dir, filename))
+		if err != nil {
+			return nil, err
+		}
+		hash.Write(file)
+
+		var currentFilePrograms map[string]map[string]Program
+		err = json.Unmarshal(file, &currentFilePrograms)
+		if err != nil {
+			return nil, fmt.Errorf("無法解析 %s: %w", filename, err)
+		}
+
+		for college, collegePrograms := range currentFilePrograms {
+			if _, ok := c.byCollege[college]; !ok {
+				c.byCollege[college] = make(map[string]Program)
+			}
+			for id, p := range collegePrograms {
+				p.Type = pType // 標記學程類型
+				// 學程 ID 在所有定義檔中須唯一 (同名的學分學程與微學程請使用不同 ID，例如 museum 與 museum_micro)
+				if existing, dup := c.programs[id]; dup {
+					dupErrs = append(dupErrs, fmt.Errorf("%s 中的學程 ID %s 重複 (已用於%s)", filename, id, existing.Name))
+					continue
+				}
+				c.byCollege[college][id] = p
+				c.programs[id] = p
+			}
+		}
+	}
+	if len(dupErrs) > 0 {
+		// 依訊息排序，使錯誤內容不受 map 走訪順序影響
+		slices.SortFunc(dupErrs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+		return nil, fmt.Errorf("學程 ID 重複: %w", errors.Join(dupErrs...))
+	}
+
+	// Post-process "跨院" (Interdisciplinary) programs
+	if interdisciplinary, ok := c.byCollege["跨院"]; ok {
+		delete(c.byCollege, "跨院") // Remove the category
+
+		for id, p := range interdisciplinary {
+			originalName := p.Name
+			start := strings.LastIndex(originalName, "（")
+			end := strings.LastIndex(originalName, "）")
+
+			if start != -1 && end != -1 && end > start {
+				collegesPart := originalName[start+len("（") : end]
+				colleges := strings.Split(collegesPart, " x ")
+
+				p.Name = originalName[:start]
+				c.programs[id] = p
+
+				for _, college := range colleges {
+					college = strings.TrimSpace(college)
+					if _, exists := c.byCollege[college]; !exists {
+						c.byCollege[college] = make(map[string]Program)
+					}
+					c.byCollege[college][id] = p
+				}
+			}
+		}
+	}
+
+	if c.compiled, c.byCourseName, err = compilePrograms(c.programs); err != nil {
+		return nil, err
+	}
+	c.index = buildProgramIndex(c)
+	c.Version = hex.EncodeToString(hash.Sum(nil))[:12]
+	return c, nil
+}
+
+// 學程 ID 是否存在於目錄中
+func (c *Catalog) Has(id string) bool {
+	_, ok := c.programs[id]
+	return ok
+}
+
+// 目錄中所有學程 ID (已排序)
+func (c *Catalog) IDs() []string {
+	return slices.Clone(c.index.sortedIDs)
+}
+
+// 依學院分類的學程定義 (舊版 /api/programs 的格式；回傳內容為複本)
+func (c *Catalog) ByCollege() map[string]map[string]Program {
+	byCollege := make(map[string]map[string]Program, len(c.byCollege))
+	for college, programs := range c.byCollege {
+		byCollege[college] = maps.Clone(programs)
+	}
+	return byCollege
+}
@@ -0,0 +1,353 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// --- 檢核器 (學程目錄 + 系所資料) ---
+
+// Checker 載入後不再修改，可同時由多個 goroutine 使用；
+// 不同學年度的學程目錄各自建立 Checker 即可在同一行程中並存。
+type Checker struct {
+	catalog     *Catalog
+	departments *Departments
+	transcripts transcriptRegistry // 成績檔格式登錄表
+	trace       RuleTracer         // nil 表示不追蹤
+}
+
+// 以學程目錄與系所資料建立檢核器
+func New(catalog *Catalog, departments *Departments) *Checker {
+	return &Checker{catalog: catalog, departments: departments, transcripts: defaultTranscriptRegistry}
+}
+
+// 特殊規則實際改變檢核結果 (剔除或減少學分、調整分類歸屬、使要求未達成) 時呼叫的函式，
+// rule 為規則識別碼；CheckAll 與 Recommend 會由多個 goroutine 同時呼叫
+type RuleTracer func(programID, rule string)
+
+func (t RuleTracer) fire(programID, rule string) {
+	if t != nil {
+		t(programID, rule)
+	}
+}
+
+// 回傳共用同一學程目錄、但會將特殊規則的套用情形回報給 trace 的檢核器 (原檢核器不受影響)
+func (c *Checker) WithRuleTracer(trace RuleTracer) *Checker {
+	traced := *c
+	traced.trace = trace
+	return &traced
+}
+
+// 由資料目錄載入學程目錄與系所資料
+func Load(dir string) (*Checker, error) {
+	catalog, err := LoadCatalog(dir)
+	if err != nil {
+		return nil, fmt.Errorf("初始化失敗: %w", err)
+	}
+	departments, err := LoadDepartments(dir)
+	if err != nil {
+		return nil, fmt.Errorf("載入系所資料失敗: %w", err)
+	}
+	return New(catalog, departments), nil
+}
+
+// 檢核器使用的學程目錄
+func (c *Checker) Catalog() *Catalog {
+	return c.catalog
+}
+
+// 檢核器使用的系所資料
+func (c *Checker) Departments() *Departments {
+	return c.departments
+}
+
+// 平行檢核多個學程，結果依 ids 的順序排列 (ids 須皆存在於目錄中)
+func (c *Checker) CheckAll(ids []string, courses []StudentCourse, profile StudentProfile) []CheckResult {
+	buckets := c.catalog.coursesByProgram(courses)
+	results := make([]CheckResult, len(ids))
+	workers := min(runtime.GOMAXPROCS(0), len(ids))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], _ = c.Check(ids[i], buckets[ids[i]], profile)
+			}
+		}()
+	}
+	for i := range ids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// 學程 ID 不存在時回傳的錯誤
+var ErrProgramNotFound = errors.New("學程 ID 不存在")
+
+// --- 輔助函式 ---
+
+// 檢查分數是否及格 (Go 實作)
+func isPassed(scoreStr string) bool {
+	const PASSING_SCORE = 60.0
+	score, err := strconv.ParseFloat(scoreStr, 64)
+	return err == nil && score >= PASSING_SCORE
+}
+
+// 檢查是否修習中
+func isInProgress(scoreStr string) bool {
+	return strings.TrimSpace(scoreStr) == "成績未到或無成績"
+}
+
+// 核心檢核邏輯 (與原 JS checkProgramCompletion 邏輯對應)
+// 檢核學生課程是否符合指定學分學程的要求。
+// 注意：本函式僅讀取檢核器的學程目錄，可同時由多個 goroutine 呼叫
+func (c *Checker) Check(programID string, courses []StudentCourse, profile StudentProfile) (CheckResult, error) {
+	program, ok := c.catalog.compiled[programID]
+	if !ok {
+		return CheckResult{}, fmt.Errorf("%w: %s", ErrProgramNotFound, programID)
+	}
+
+	// 階段 1: 預處理學程要求 (載入時已編譯；分類清單可能於階段 2 調整，故複製一份)
+	localRequirements := append([]ProgramRequirement(nil), program.requirements...)
+	geCourseNames := program.geCourses
+
+	// 階段 2: 篩選並處理課程
+	completedCourses, inProgressCourses := filterAndProcessCourses(c.trace, programID, courses, &localRequirements, program.courseNames, geCourseNames, program.instructors)
+
+	// 檢查是否有通識課程超限 (用於後續顯示)
+	geLimitExceeded := false
+	if program.geListed {
+		geCount := 0
+		for _, c := range completedCourses {
+			if geCourseNames[c.Name] {
+				geCount++
+			}
+		}
+		// filterAndProcessCourses 已經將多餘的通識課程移除，所以這裡我們比較原始數量
+		// 但因為我們沒有保留原始的 relevantPassed，這裡可以用一個簡單的邏輯：
+		// 如果 filterAndProcessCourses 已經處理了，我們其實不需要知道是否超限，除非要顯示警告。
+		// 為了顯示警告，我們可以在這裡重新檢查原始輸入中符合通識的數量。
+		rawGeCount := 0
+		for _, c := range courses {
+			if geCourseNames[c.Name] && c.IsPassed {
+				rawGeCount++
+			}
+		}
+		if rawGeCount > 1 {
+			geLimitExceeded = true
+		}
+	}
+
+	totalPassedCredits := 0.0 // 將由後續計算有效學分決定
+
+	// 步驟 2 & 3: 檢核分類要求 (門數) 和總學分
+	categoryResults := []CategoryResult{}
+
+	// 特殊處理：管理會計專業學程 (CIMA)
+	isManagementAccounting := programID == "CIMA"
+
+	if isManagementAccounting {
+		var isMet bool
+		categoryResults, isMet, totalPassedCredits = processManagementAccounting(c.trace, program, completedCourses)
+		// allCategoriesMet 將在 postprocessResults 中統一計算
+		_ = isMet
+	} else {
+		// 一般學程邏輯：呼叫 special_handlers.go 中的函式
+		categories := program.categories
+		if program.adjustsRequirements {
+			categories = newCategoryIndex(localRequirements)
+		}
+		categoryResults, totalPassedCredits = processStandardRequirements(localRequirements, completedCourses, categories)
+
+		// 如果有通識課程超限，加入一個額外的分類結果顯示
+		if program.geListed && geLimitExceeded {
+			// 找出被採計的通識課程 (在 filterAndProcessCourses 中已處理為僅剩一門或零門)
+			var gePassed []StudentCourse
+			for _, c := range completedCourses {
+				if geCourseNames[c.Name] {
+					gePassed = append(gePassed, c)
+				}
+			}
+			geCredits := 0.0
+			for _, c := range gePassed {
+				geCredits += c.Credit
+			}
+			categoryResults = append(categoryResults, CategoryResult{
+				Category:        "通識課程 (全域限制)",
+				RequiredCount:   1, // 顯示限制
+				PassedCount:     len(gePassed),
+				PassedCredits:   geCredits,
+				IsMet:           true,
+				PassedCourses:   gePassed,
+				LimitExceeded:   true,
+				ExceededMessage: "通識課程認列以一門為限 (已自動採計最高分者)",
+			})
+		}
+	}
+
+	// 階段 3: 後處理 (跨群檢核、平均成績、系所限制等)
+	categoryResults, allCategoriesMet, restrictionMessage, avgScoreRequired, avgScoreStr, avgScoreMet, avgScoreThreshold, totalPassedCredits := postprocessResults(c.trace, programID, program, profile, categoryResults, totalPassedCredits)
+
+	// 步驟 4: 總結
+	totalCreditsMet := totalPassedCredits >= program.minCredits
+	isCompleted := totalCreditsMet && allCategoriesMet
+
+	// 若有平均成績要求且未達標，則視為未修畢
+	if avgScoreRequired && !avgScoreMet {
+		isCompleted = false
+	}
+
+	return CheckResult{
+		ProgramName:        program.name,
+		ProgramURL:         program.url,
+		IsCompleted:        isCompleted,
+		TotalPassedCredits: fmt.Sprintf("%.1f", totalPassedCredits),
+		MinRequiredCredits: fmt.Sprintf("%.1f", program.minCredits),
+		TotalCreditsMet:    totalCreditsMet,
+		AllCategoriesMet:   allCategoriesMet,
+		CategoryResults:    categoryResults,
+		InProgressCourses:  inProgressCourses,
+		ProgramDescription: program.description,
+		AvgScoreRequired:   avgScoreRequired,
+		AvgScore:           avgScoreStr,
+		AvgScoreMet:        avgScoreMet,
+		AvgScoreThreshold:  avgScoreThreshold,
+		RestrictionMessage: restrictionMessage,
+	}, nil
+}
+
+// 計算檢核結果的完成度，並回傳主學程已修、應修學分與先修已修學分
+func CompletionRate(result CheckResult) (rate, passed, min, passedPrereq float64) {
+	passed, _ = strconv.ParseFloat(result.TotalPassedCredits, 64)
+	min, _ = strconv.ParseFloat(result.MinRequiredCredits, 64)
+
+	// 計算先修課程學分 (用於加分與計算百分比，但不計入 TotalPassedCredits)
+	requiredPrereq := 0.0
+	for _, cat := range result.CategoryResults {
+		if strings.HasPrefix(cat.Category, "先修課程") {
+			passedPrereq += cat.PassedCredits
+			requiredPrereq += cat.RequiredCredits
+		}
+	}
+
+	// 計算完成度：(主學程已修 + 先修已修) / (主學程應修 + 先修應修)
+	totalPassed := passed + passedPrereq
+	totalRequired := min + requiredPrereq
+	if totalRequired > 0 {
+		rate = totalPassed / totalRequired
+	}
+	return rate, passed, min, passedPrereq
+}
+
+// 列入平均成績的課程 (依學分加權；先修分類不列入，同一學期的同名課程只計一次)，
+// 與檢核時計算 AvgScore 所用的課程相同，供報表列出平均成績明細
+func AvgScoreCourses(result CheckResult) []StudentCourse {
+	return avgScoreCourses(result.CategoryResults)
+}
+
+func avgScoreCourses(categoryResults []CategoryResult) []StudentCourse {
+	var courses []StudentCourse
+	seen := make(map[string]bool)
+	for _, res := range categoryResults {
+		if strings.Contains(res.Category, "先修") {
+			continue
+		}
+		for _, c := range res.PassedCourses {
+			key := c.Name + "-" + c.Semester
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if _, err := strconv.ParseFloat(c.Score, 64); err == nil {
+				courses = append(courses, c)
+			}
+		}
+	}
+	return courses
+}
+
+// 遍歷所有學程進行檢核，回傳完成度前五名 (包含並列) 的學程
+func (c *Checker) Recommend(studentCourses []StudentCourse, profile StudentProfile) []Recommendation {
+	// 各學程的檢核彼此獨立，平行執行 (結果依學程 ID 排序，確保並列時的輸出穩定)
+	ids := c.catalog.index.sortedIDs
+	return c.RecommendFromResults(ids, c.CheckAll(ids, studentCourses, profile), profile)
+}
+
+// 由已取得的檢核結果 (與 ids 順序相同，例如部分來自快取) 產生推薦結果；
+// ids 為 Catalog().IDs() 時與 Recommend 的結果相同，不在目錄中的 ID 略過
+func (c *Checker) RecommendFromResults(ids []string, results []CheckResult, profile StudentProfile) []Recommendation {
+	var recommendations []Recommendation
+
+	for i, id := range ids {
+		program, ok := c.catalog.compiled[id]
+		if !ok {
+			continue
+		}
+		// 學生不符合資格限制 (例如限商學院學生) 的學程仍列出，但標示為受限
+		isRestricted := !program.eligible(profile)
+
+		result := results[i]
+		rate, passed, min, passedPrereq := CompletionRate(result)
+
+		// 推薦門檻：完成度達 20% 以上 (避免僅修一門通識就推薦所有學程)
+		if rate >= 0.2 {
+			recommendations = append(recommendations, Recommendation{
+				ProgramID:           id,
+				ProgramName:         program.name,
+				ProgramURL:          program.url,
+				Type:                program.programType,
+				TotalPassedCredits:  passed,
+				MinCredits:          min,
+				PassedPrereqCredits: passedPrereq,
+				CompletionRate:      rate,
+				IsCompleted:         result.IsCompleted,
+				IsRestricted:        isRestricted,
+				CategoryResults:     result.CategoryResults,
+			})
+		}
+	}
+
+	// 排序：依完成度由高至低
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].CompletionRate > recommendations[j].CompletionRate
+	})
+
+	// 6. 篩選前五名 (包含並列)
+	var topRecommendations []Recommendation
+	if len(recommendations) > 0 {
+		currentRank := 1
+		lastRate := recommendations[0].CompletionRate
+
+		for _, rec := range recommendations {
+			// 若完成度小於上一筆，則名次遞增
+			if rec.CompletionRate < lastRate {
+				currentRank++
+				lastRate = rec.CompletionRate
+			}
+
+			// 若名次已超過 5，則停止加入
+			if currentRank > 5 {
+				break
+			}
+
+			topRecommendations = append(topRecommendations, rec)
+		}
+	}
+
+	return topRecommendations
+}
+
+// 輔助函式：標準化課程名稱，確保比對準確
+func normalizeCourseName(name string) string {
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// 以 data/ 建立的檢核器 (各測試共用)
+var testChecker *Checker
+
+func TestMain(m *testing.M) {
+	var err error
+	if testChecker, err = Load("../data"); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// 不同學程目錄的檢核器可在同一行程中並存，彼此的結果互不影響
+func TestCheckersCoexist(t *testing.T) {
+	catalog, err := LoadCatalog("testdata/catalog_alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alt := New(catalog, testChecker.departments)
+	if catalog.Version == "" || catalog.Version == testChecker.catalog.Version {
+		t.Errorf("目錄版本 %q 應與 data/ 的 %q 不同", catalog.Version, testChecker.catalog.Version)
+	}
+
+	data, err := os.ReadFile("testdata/transcript_sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript, err := alt.ParseTranscript(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	courses, profile := transcript.Courses, transcript.Profile
+
+	result, err := alt.Check("CFA", courses, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ProgramName != "CFA 學程 (測試用舊版)" || !result.IsCompleted {
+		t.Errorf("測試目錄的檢核結果 %+v", result)
+	}
+	current, err := testChecker.Check("CFA", courses, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.ProgramName == result.ProgramName {
+		t.Errorf("data/ 的檢核器不應受測試目錄影響")
+	}
+	if _, err := alt.Check("fintech", courses, profile); err == nil {
+		t.Errorf("測試目錄中沒有 fintech，應回傳錯誤")
+	}
+	if recs := alt.Recommend(courses, profile); len(recs) != 1 || recs[0].ProgramID != "CFA" {
+		t.Errorf("測試目錄的推薦結果 %+v", recs)
+	}
+
+	// 課程別名屬於各自的目錄
+	if got := catalog.courseKey("經濟學原理"); got != "經濟學" {
+		t.Errorf("測試目錄的別名未套用: %q", got)
+	}
+}
+
+// AvgScoreCourses 列出的課程依學分加權後須等於檢核結果的平均成績 (報表的平均成績明細以此列出)
+func TestAvgScoreCourses(t *testing.T) {
+	courses, _ := readTestTranscript(t, "transcript_sample.json")
+	result, err := testChecker.Check("CFA", courses, StudentProfile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := AvgScoreCourses(result)
+	if !result.AvgScoreRequired || len(listed) == 0 {
+		t.Fatalf("CFA 應檢核平均成績並列出課程: %+v", result)
+	}
+	total, credits := 0.0, 0.0
+	for _, c := range listed {
+		score, err := strconv.ParseFloat(c.Score, 64)
+		if err != nil {
+			t.Fatalf("%s 的成績 %q 不是數字", c.Name, c.Score)
+		}
+		total += score * c.Credit
+		credits += c.Credit
+	}
+	if got := fmt.Sprintf("%.2f", total/credits); got != result.AvgScore {
+		t.Errorf("列出課程的平均 %s，檢核結果為 %s", got, result.AvgScore)
+	}
+}
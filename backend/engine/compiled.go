@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// --- 預先編譯的學程 (於 LoadCatalog 時建立，檢核時唯讀共用) ---
+//
+// Program 為學程定義檔的格式；compiledProgram 為檢核使用的內部格式，
+// 名稱已正規化、特殊格式已解析，並於載入時驗證，之後不再修改。
+
+// 課程所屬的分類集合：第 i 個位元代表學程的第 i 個分類
+type categorySet uint64
+
+// 單一學程最多可有的分類數 (categorySet 的位元數)
+const maxCategories = 64
+
+// 課程名稱 -> 所屬分類
+type categoryIndex map[string]categorySet
+
+func newCategoryIndex(reqs []ProgramRequirement) categoryIndex {
+	idx := make(categoryIndex)
+	for i, req := range reqs {
+		for _, name := range req.Courses {
+			idx[name] |= 1 << i
+		}
+	}
+	return idx
+}
+
+// 學程的資格限制 (例如限商學院學生)
+type eligibilityRule struct {
+	allow   func(StudentProfile) bool
+	message string
+}
+
+// 已編譯的學程；內容不可修改，需依學生修課調整分類時先複製 requirements
+type compiledProgram struct {
+	id          string
+	name        string
+	programType string
+	description string
+	url         string
+	minCredits  float64
+
+	requirements []ProgramRequirement // 課程名稱已去除前後空白與重複，並去除授課教師標註
+	courseNames  map[string]bool      // 學程內所有課程 (含通識)
+	geCourses    map[string]bool      // 通識課程 (全域限修一門)
+	geSorted     []string             // 依名稱排序 (學程詳細資料使用)
+	geListed     bool                 // 學程定義檔中列有通識課程 (超限時另外顯示通識分類)
+	instructors  map[string]string    // 課程 -> 授課教師 (東南亞區域研究微學程)
+	categories   categoryIndex
+
+	eligibility         *eligibilityRule // 無資格限制時為 nil
+	rules               []string         // 特殊規則說明
+	adjustsRequirements bool             // 是否於 filterAndProcessCourses 中依學生修課調整分類課程清單
+}
+
+// 會在 filterAndProcessCourses 中依學生修課調整分類課程清單的學程，檢核時需重建分類索引
+var requirementsAdjustedPrograms = map[string]bool{
+	"patent":  true,
+	"fintech": true,
+}
+
+// 編譯目錄中的所有學程，並回傳課程名稱 -> 學程 ID 的對照；定義有誤的學程會一併列出
+func compilePrograms(programs map[string]Program) (map[string]*compiledProgram, map[string][]string, error) {
+	compiled := make(map[string]*compiledProgram, len(programs))
+	byCourse := make(map[string][]string)
+
+	ids := make([]string, 0, len(programs))
+	for id := range programs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var errs []error
+	for _, id := range ids {
+		cp, err := compileProgram(id, programs[id])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		compiled[id] = cp
+		for name := range cp.courseNames {
+			byCourse[name] = append(byCourse[name], id)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("學程定義有誤: %w", errors.Join(errs...))
+	}
+	return compiled, byCourse, nil
+}
+
+func compileProgram(id string, p Program) (*compiledProgram, error) {
+	if err := validateProgram(p); err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+
+	reqs, courseNames, geCourses, instructors := preprocessRequirements(id, p)
+	geSorted := make([]string, 0, len(geCourses))
+	for name := range geCourses {
+		geSorted = append(geSorted, name)
+	}
+	sort.Strings(geSorted)
+
+	cp := &compiledProgram{
+		id:                  id,
+		name:                p.Name,
+		programType:         p.Type,
+		description:         p.Description,
+		url:                 p.URL,
+		minCredits:          p.MinCredits,
+		requirements:        reqs,
+		courseNames:         courseNames,
+		geCourses:           geCourses,
+		geSorted:            geSorted,
+		geListed:            len(p.GeneralEducationCourses) > 0,
+		instructors:         instructors,
+		categories:          newCategoryIndex(reqs),
+		rules:               programRules(id, p),
+		adjustsRequirements: requirementsAdjustedPrograms[id],
+	}
+	if rule, ok := eligibilityRules[id]; ok {
+		cp.eligibility = &rule
+	}
+	return cp, nil
+}
+
+// 驗證學程定義 (只檢查會使檢核結果錯誤的問題)
+func validateProgram(p Program) error {
+	var errs []error
+	if strings.TrimSpace(p.Name) == "" {
+		errs = append(errs, fmt.Errorf("缺少學程名稱"))
+	}
+	if p.MinCredits < 0 {
+		errs = append(errs, fmt.Errorf("min_credits 不可為負數"))
+	}
+	if len(p.Requirements) == 0 {
+		errs = append(errs, fmt.Errorf("缺少認列分類"))
+	}
+	if len(p.Requirements) > maxCategories {
+		errs = append(errs, fmt.Errorf("分類數 (%d) 超過上限 %d", len(p.Requirements), maxCategories))
+	}
+	for _, req := range p.Requirements {
+		switch {
+		case strings.TrimSpace(req.Category) == "":
+			errs = append(errs, fmt.Errorf("分類名稱空白"))
+		case req.MinCount < 0 || req.MaxCount < 0 || req.MinCredits < 0 || req.MaxCredits < 0:
+			errs = append(errs, fmt.Errorf("分類 %s 的門數或學分不可為負數", req.Category))
+		case req.MaxCount > 0 && req.MinCount > req.MaxCount:
+			errs = append(errs, fmt.Errorf("分類 %s 的 min_count (%d) 大於 max_count (%d)", req.Category, req.MinCount, req.MaxCount))
+		case req.MaxCredits > 0 && req.MinCredits > req.MaxCredits:
+			errs = append(errs, fmt.Errorf("分類 %s 的 min_credits (%.1f) 大於 max_credits (%.1f)", req.Category, req.MinCredits, req.MaxCredits))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 依學程分派學生課程 (保留原順序)：每個學程只會收到其課程清單中的課程，
+// 檢核結果與傳入完整課程列表相同
+func (c *Catalog) coursesByProgram(courses []StudentCourse) map[string][]StudentCourse {
+	buckets := make(map[string][]StudentCourse)
+	for _, course := range courses {
+		for _, id := range c.byCourseName[course.Name] {
+			buckets[id] = append(buckets[id], course)
+		}
+	}
+	return buckets
+}
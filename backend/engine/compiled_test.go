@@ -0,0 +1,275 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 大型成績：目錄中所有學程的課程各修一次，並重複於數個學期 (約為學生成績的數十倍)
+func largeTranscript(semesters int) []StudentCourse {
+	var courses []StudentCourse
+	for s := 0; s < semesters; s++ {
+		for _, id := range testChecker.catalog.IDs() {
+			for _, req := range testChecker.catalog.programs[id].Requirements {
+				for i, name := range req.Courses {
+					score := fmt.Sprint(55 + (i*7+s*3)%45)
+					courses = append(courses, StudentCourse{
+						Name:     name,
+						Credit:   float64(1 + i%3),
+						Score:    score,
+						IsPassed: isPassed(score),
+						Semester: fmt.Sprintf("%d-%d", 110+s/2, 1+s%2),
+					})
+				}
+			}
+		}
+	}
+	return courses
+}
+
+// 平行檢核的結果應與逐一檢核相同
+func TestCheckProgramsParallel(t *testing.T) {
+	courses := largeTranscript(2)
+	profile := StudentProfile{Major: "財務管理學系", DepartmentCode: "307", CollegeCode: businessCollegeCode}
+	ids := testChecker.catalog.IDs()
+
+	got := testChecker.CheckAll(ids, courses, profile)
+	for i, id := range ids {
+		want, err := testChecker.Check(id, courses, profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got[i], want) {
+			t.Errorf("%s: 平行檢核結果不一致", id)
+		}
+	}
+
+	first := testChecker.Recommend(courses, profile)
+	for i := 0; i < 5; i++ {
+		if again := testChecker.Recommend(courses, profile); !reflect.DeepEqual(again, first) {
+			t.Fatal("推薦結果 (含順序) 應每次相同")
+		}
+	}
+}
+
+// 依學生修課調整分類的學程不得改動已編譯的共用資料
+func TestCompiledProgramsImmutable(t *testing.T) {
+	before := make(map[string][]ProgramRequirement)
+	for id := range requirementsAdjustedPrograms {
+		before[id] = append([]ProgramRequirement(nil), testChecker.catalog.compiled[id].requirements...)
+	}
+	courses := largeTranscript(1)
+	for id := range requirementsAdjustedPrograms {
+		if _, err := testChecker.Check(id, courses, StudentProfile{}); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(testChecker.catalog.compiled[id].requirements, before[id]) {
+			t.Errorf("%s: 檢核後已編譯的分類被修改", id)
+		}
+	}
+}
+
+func TestCompileProgram(t *testing.T) {
+	cp, err := compileProgram("southeast_asian_area_studies", Program{
+		Name:       "測試學程",
+		MinCredits: 6,
+		Requirements: []ProgramRequirement{
+			{Category: "核心", Courses: []string{"越南語(王老師)", " 泰國社會 ", "泰國社會"}},
+		},
+		GeneralEducationCourses: []string{"通識 "},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cp.requirements[0].Courses; !reflect.DeepEqual(got, []string{"越南語", "泰國社會"}) {
+		t.Errorf("課程名稱應去除教師標註、空白與重複，得到 %q", got)
+	}
+	if cp.instructors["越南語"] != "王老師" || !cp.geCourses["通識"] || !cp.courseNames["泰國社會"] {
+		t.Errorf("編譯結果 %+v", cp)
+	}
+	if cp.categories["泰國社會"] != 1 {
+		t.Errorf("分類索引 %v", cp.categories)
+	}
+
+	invalid := []Program{
+		{MinCredits: 6, Requirements: []ProgramRequirement{{Category: "核心"}}},
+		{Name: "無分類", MinCredits: 6},
+		{Name: "門數", Requirements: []ProgramRequirement{{Category: "核心", MinCount: 3, MaxCount: 2}}},
+		{Name: "學分", Requirements: []ProgramRequirement{{Category: "核心", MinCredits: 6, MaxCredits: 3}}},
+		{Name: "分類", Requirements: []ProgramRequirement{{Category: " "}}},
+	}
+	for _, p := range invalid {
+		if _, err := compileProgram("invalid", p); err == nil {
+			t.Errorf("%+v 應驗證失敗", p)
+		}
+	}
+}
+
+// 同名的學分學程與微學程以不同 ID 定義，兩者皆須可用
+func TestSameNameProgramTypes(t *testing.T) {
+	for id, wantType := range map[string]string{"museum": "credit", "museum_micro": "micro"} {
+		p, ok := testChecker.catalog.compiled[id]
+		if !ok || p.programType != wantType {
+			t.Errorf("%s: 預期類型 %s，得到 %+v", id, wantType, p)
+		}
+	}
+}
+
+// 學程 ID 重複 (即使學程類型不同) 時載入失敗，而非擇一使用或自動改名；所有重複的 ID 一併列出
+func TestLoadCatalogDuplicateIDs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"course_aliases.json", "credit_programs.json"} {
+		data, err := os.ReadFile(filepath.Join("testdata/catalog_alt", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"micro_programs.json":              `{"商學院": {"CFA": {"name": "CFA 微學程", "min_credits": 3, "requirements": [{"category": "核心", "min_count": 1, "courses": ["投資學"]}]}, "risk": {"name": "風險管理微學程", "min_credits": 3, "requirements": [{"category": "核心", "min_count": 1, "courses": ["風險管理"]}]}}}`,
+		"commerce_specialty_programs.json": `{"商學院": {"risk": {"name": "風險管理專長", "min_credits": 3, "requirements": [{"category": "核心", "min_count": 1, "courses": ["風險管理"]}]}}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := LoadCatalog(dir)
+	if err == nil {
+		t.Fatal("預期學程 ID 重複的錯誤")
+	}
+	for _, id := range []string{"CFA", "risk"} {
+		if !strings.Contains(err.Error(), "學程 ID "+id+" 重複") {
+			t.Errorf("錯誤訊息應列出重複的 %s，得到 %v", id, err)
+		}
+	}
+}
+
+func benchmarkTranscripts(b *testing.B) map[string][]StudentCourse {
+	b.Helper()
+	sample, _ := readTestTranscript(&testing.T{}, "transcript_sample.json")
+	return map[string][]StudentCourse{
+		"sample": sample,
+		"large":  largeTranscript(1),
+		"huge":   largeTranscript(8),
+	}
+}
+
+// 單一學程檢核 (全目錄逐一執行)
+func BenchmarkCheckAllPrograms(b *testing.B) {
+	for name, courses := range benchmarkTranscripts(b) {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				for _, id := range testChecker.catalog.IDs() {
+					testChecker.Check(id, courses, StudentProfile{})
+				}
+			}
+		})
+	}
+}
+
+// 全目錄平行檢核
+func BenchmarkCheckAllProgramsParallel(b *testing.B) {
+	for name, courses := range benchmarkTranscripts(b) {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				testChecker.CheckAll(testChecker.catalog.IDs(), courses, StudentProfile{})
+			}
+		})
+	}
+}
+
+// 推薦 (/api/recommend 的核心)
+func BenchmarkRecommendPrograms(b *testing.B) {
+	for name, courses := range benchmarkTranscripts(b) {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				testChecker.Recommend(courses, StudentProfile{})
+			}
+		})
+	}
+}
+
+// 學程預處理 (載入時執行一次，檢核時不再重複)
+func BenchmarkCompilePrograms(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, _, err := compilePrograms(testChecker.catalog.programs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// 特殊規則依學程 ID 與分類名稱套用：學程改名或分類改名時規則會默默失效，
+// 學程詳細資料卻仍列出該規則，因此確認每條規則依賴的學程與分類皆存在於目錄中
+func TestSpecialRulesMatchCatalog(t *testing.T) {
+	hasPrefix := func(prefix string) func(string) bool {
+		return func(category string) bool { return strings.HasPrefix(category, prefix) }
+	}
+	contains := func(substr string) func(string) bool {
+		return func(category string) bool { return strings.Contains(category, substr) }
+	}
+	equals := func(name string) func(string) bool {
+		return func(category string) bool { return category == name }
+	}
+	// 學程 ID -> 特殊處理比對分類名稱的方式 (與 special_handlers.go 相同)
+	categories := map[string][]func(string) bool{
+		"patent":                             {hasPrefix("商學院"), hasPrefix("法學院")},
+		"fintech":                            {contains("群A"), contains("群B"), contains("選修C")},
+		"interdisciplinary_precision_health": {contains("群A"), contains("群B"), contains("群C"), contains("群D")},
+		"southeast_asia_culture_religion_interdisciplinary": {contains("語言領域")},
+		"human_resource_management_undergraduate":           {equals("程序課程：管理類"), equals("程序課程：勞工關係類"), equals("程序課程：行為類")},
+		"human_resource_management_master":                  {equals("必修：管理心理學"), equals("程序課程：行為類")},
+		"marketing_undergraduate":                           {equals("選修課程")},
+		"marketing_master":                                  {equals("選修課程")},
+	}
+
+	ids := []string{"CIMA"} // 於 Checker.Check 中直接處理
+	for id := range specialRules {
+		ids = append(ids, id)
+	}
+	for id := range eligibilityRules {
+		ids = append(ids, id)
+	}
+	for id := range requirementsAdjustedPrograms {
+		ids = append(ids, id)
+	}
+	for _, id := range ids {
+		if !testChecker.catalog.Has(id) {
+			t.Errorf("特殊規則對應的學程 %s 不在目錄中", id)
+		}
+	}
+
+	for id, matchers := range categories {
+		program := testChecker.catalog.compiled[id]
+		if program == nil {
+			t.Errorf("學程 %s 不在目錄中", id)
+			continue
+		}
+		for i, match := range matchers {
+			found := false
+			for _, req := range program.requirements {
+				if match(req.Category) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("學程 %s 缺少特殊規則所需的第 %d 個分類", id, i+1)
+			}
+		}
+	}
+	// 跨領域精準健康學分學程的特殊處理以學程名稱判斷
+	if name := testChecker.catalog.compiled["interdisciplinary_precision_health"].name; name != "跨領域精準健康學分學程" {
+		t.Errorf("精準健康學程名稱 %q 與特殊處理不符", name)
+	}
+}
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// --- 課程名稱比對鍵與別名 (用於反查課程所屬學程) ---
+
+// 括號內的序號統一為阿拉伯數字
+var courseNumeralReplacer = strings.NewReplacer(
+	"(一)", "(1)", "(二)", "(2)", "(三)", "(3)", "(四)", "(4)",
+	"(i)", "(1)", "(ii)", "(2)", "(iii)", "(3)", "(iv)", "(4)",
+)
+
+// courseKey 將課程名稱轉為比對鍵：全形轉半形、去除空白、統一「臺/台」與括號序號，並套用目錄的課程別名
+func (c *Catalog) courseKey(name string) string {
+	key := courseNameKey(name)
+	if canonical, ok := c.courseAliases[key]; ok {
+		return canonical
+	}
+	return key
+}
+
+// 不含別名的課程比對鍵
+func courseNameKey(name string) string {
+	return courseNumeralReplacer.Replace(nameKey(name))
+}
+
+// nameKey 將名稱正規化 (課程與系所名稱共用)：全形轉半形、去除空白、統一「臺/台」並轉小寫
+func nameKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E: // 全形 ASCII
+			r -= 0xFEE0
+		case r == '—' || r == '–':
+			r = '-'
+		case r == '臺':
+			r = '台'
+		}
+		if unicode.IsSpace(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// 解析課程別名定義 (course_aliases.json)，回傳比對鍵的對照
+func parseCourseAliases(file []byte) (map[string]string, error) {
+	var aliases map[string]string
+	if err := json.Unmarshal(file, &aliases); err != nil {
+		return nil, fmt.Errorf("無法解析 course_aliases.json: %w", err)
+	}
+
+	keys := make(map[string]string, len(aliases))
+	for alias, canonical := range aliases {
+		keys[courseNameKey(alias)] = courseNameKey(canonical)
+	}
+	return keys, nil
+}
+
+// 課程可認列的學程與分類
+type CourseProgramRef struct {
+	ProgramID   string `json:"programID"`
+	ProgramName string `json:"programName"`
+	Type        string `json:"type"`
+	Category    string `json:"category"`
+}
+
+// 單一已通過課程的反查結果
+type CourseContribution struct {
+	Name     string             `json:"name"`
+	Semester string             `json:"semester"`
+	Credit   float64            `json:"credit"`
+	Score    string             `json:"score"`
+	Programs []CourseProgramRef `json:"programs"`
+}
+
+// 列出每門已通過課程出現在哪些學程的認列清單中
+func (c *Catalog) CourseContributions(courses []StudentCourse) []CourseContribution {
+	results := []CourseContribution{}
+	for _, course := range courses {
+		if !course.IsPassed {
+			continue
+		}
+		refs := []CourseProgramRef{}
+		for _, ref := range c.index.courses[c.courseKey(course.Name)] {
+			p := c.programs[ref.ProgramID]
+			refs = append(refs, CourseProgramRef{
+				ProgramID:   ref.ProgramID,
+				ProgramName: p.Name,
+				Type:        p.Type,
+				Category:    ref.Category,
+			})
+		}
+		results = append(results, CourseContribution{
+			Name:     course.Name,
+			Semester: course.Semester,
+			Credit:   course.Credit,
+			Score:    course.Score,
+			Programs: refs,
+		})
+	}
+	return results
+}
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- 系所資料 (依代碼、名稱與舊名查詢所屬學院與學制) ---
+
+// 商學院在 departments_grouped.json 中的分組代碼
+const businessCollegeCode = "3"
+
+// 單一系所
+type Department struct {
+	Code        string   `json:"code"`
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases"` // 舊名或其他寫法 (data/department_aliases.json)
+	CollegeCode string   `json:"collegeCode"`
+	College     string   `json:"college"`
+	DegreeLevel string   `json:"degreeLevel,omitempty"` // 由系所名稱推斷；學系為 bachelor
+}
+
+// 系所索引 (由 LoadDepartments 建立，之後唯讀)
+type Departments struct {
+	list   []Department   // 依代碼排序
+	byCode map[string]int // 代碼 -> list 中的位置
+	byName map[string]int // 名稱與別名的比對鍵 -> list 中的位置
+}
+
+// 系所名稱後可能附加的學制 (例如「金融學系碩士班」)，依長度由長到短比對
+var departmentDegreeSuffixes = []string{"碩士在職專班", "進修學士班", "在職專班", "碩士班", "博士班", "學士班"}
+
+// 載入資料目錄中的系所分類資料與系所舊名
+func LoadDepartments(dir string) (*Departments, error) {
+	file, err := os.ReadFile(filepath.Join(dir, "departments_grouped.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var groups map[string]struct {
+		CategoryName string `json:"category_name"`
+		Departments  []struct {
+			Value string `json:"value"`
+			Name  string `json:"name"`
+		} `json:"departments"`
+	}
+	if err := json.Unmarshal(file, &groups); err != nil {
+		return nil, fmt.Errorf("無法解析 departments_grouped.json: %w", err)
+	}
+
+	aliasFile, err := os.ReadFile(filepath.Join(dir, "department_aliases.json"))
+	if err != nil {
+		return nil, err
+	}
+	var aliases map[string]string // 舊名 -> 現行名稱
+	if err := json.Unmarshal(aliasFile, &aliases); err != nil {
+		return nil, fmt.Errorf("無法解析 department_aliases.json: %w", err)
+	}
+
+	idx := &Departments{byCode: make(map[string]int), byName: make(map[string]int)}
+	for code, group := range groups {
+		for _, d := range group.Departments {
+			idx.list = append(idx.list, Department{
+				Code:        d.Value,
+				Name:        d.Name,
+				Aliases:     []string{},
+				CollegeCode: code,
+				College:     group.CategoryName,
+				DegreeLevel: degreeLevelOfDepartment(d.Name),
+			})
+		}
+	}
+	sort.Slice(idx.list, func(i, j int) bool { return idx.list[i].Code < idx.list[j].Code })
+
+	for i, d := range idx.list {
+		if _, dup := idx.byCode[d.Code]; dup {
+			return nil, fmt.Errorf("departments_grouped.json 中的系所代碼 %s 重複", d.Code)
+		}
+		idx.byCode[d.Code] = i
+		idx.byName[nameKey(d.Name)] = i
+	}
+
+	names := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+	for _, alias := range names {
+		i, ok := idx.byName[nameKey(aliases[alias])]
+		if !ok {
+			return nil, fmt.Errorf("department_aliases.json: %s 對應的系所 %s 不存在", alias, aliases[alias])
+		}
+		if _, exists := idx.byName[nameKey(alias)]; !exists {
+			idx.byName[nameKey(alias)] = i
+		}
+		idx.list[i].Aliases = append(idx.list[i].Aliases, alias)
+	}
+
+	return idx, nil
+}
+
+// 依代碼、名稱或舊名找出系所。名稱不完全一致時僅嘗試去除學制後綴 (碩士班、在職專班等)；
+// 不以前綴比對，避免其他學院的名稱被誤判為商學院 (其他寫法請列入 department_aliases.json)
+func (idx *Departments) Resolve(s string) (Department, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Department{}, false
+	}
+	if i, ok := idx.byCode[strings.ToUpper(s)]; ok {
+		return idx.list[i], true
+	}
+
+	key := nameKey(s)
+	if i, ok := idx.byName[key]; ok {
+		return idx.list[i], true
+	}
+	for _, suffix := range departmentDegreeSuffixes {
+		if trimmed, ok := strings.CutSuffix(key, suffix); ok {
+			if i, ok := idx.byName[trimmed]; ok {
+				return idx.list[i], true
+			}
+		}
+	}
+	return Department{}, false
+}
+
+// 系所查詢條件
+type DepartmentQuery struct {
+	College string // 學院代碼或名稱
+	Name    string // 系所名稱 (依 resolve 解析)
+}
+
+func (idx *Departments) Search(q DepartmentQuery) []Department {
+	results := []Department{}
+	if q.Name != "" {
+		if d, ok := idx.Resolve(q.Name); ok && (q.College == "" || d.CollegeCode == q.College || d.College == q.College) {
+			results = append(results, d)
+		}
+		return results
+	}
+	for _, d := range idx.list {
+		if q.College != "" && d.CollegeCode != q.College && d.College != q.College {
+			continue
+		}
+		results = append(results, d)
+	}
+	return results
+}
@@ -0,0 +1,47 @@
+package engine
+
+import "testing"
+
+func TestResolveDepartment(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantCode string
+	}{
+		{"財務管理學系", "307"},
+		{"307", "307"},
+		{"zb1", "ZB1"},
+		{"銀行學系", "302"},    // 舊名
+		{"金融學系碩士班", "302"}, // 附加學制
+		{"國際經營與貿易學系 碩士在職專班", "301"}, // 空白與學制
+		{"企業管理研究所（ＭＢＡ學位學程）", "363"}, // 全形括號與英文
+		{"臺灣史研究所", "158"},           // 臺/台
+		{"資訊科學系碩士在職專班", "971"},      // 完全符合者優先於去除後綴
+		{"風險管理與保險學系精算科學組", ""},      // 不以前綴比對
+		{"不存在的學系", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		d, ok := testChecker.departments.Resolve(tt.input)
+		if ok != (tt.wantCode != "") || d.Code != tt.wantCode {
+			t.Errorf("resolve(%q) = %q, %v，預期 %q", tt.input, d.Code, ok, tt.wantCode)
+		}
+	}
+}
+
+// 非商學院的系所 (含舊名與附加學制的寫法) 皆不可解析為商學院，以免取得商學院限定學程的資格
+func TestResolveNonBusinessDepartments(t *testing.T) {
+	deps := testChecker.departments
+	for _, d := range deps.list {
+		if d.CollegeCode == businessCollegeCode {
+			continue
+		}
+		for _, name := range append([]string{d.Name}, d.Aliases...) {
+			for _, suffix := range append([]string{""}, departmentDegreeSuffixes...) {
+				got, ok := deps.Resolve(name + suffix)
+				if ok && got.CollegeCode == businessCollegeCode {
+					t.Errorf("%s (%s) 解析為商學院系所 %s", name+suffix, d.College, got.Name)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,12 @@
+// Package engine 為學程檢核引擎：解析成績檔、載入學程目錄與系所資料，並檢核學程完成情形與推薦學程。
+//
+// 基本用法：
+//
+//	checker, err := engine.Load("data")             // 學程定義與系所資料所在目錄
+//	transcript, err := checker.ParseTranscript(data) // 全人系統匯出的 JSON、課程列表 JSON 或 CSV
+//	result, err := checker.Check("fintech", transcript.Courses, transcript.Profile)
+//	recommendations := checker.Recommend(transcript.Courses, transcript.Profile)
+//
+// Checker 與 Catalog 載入後不再修改，可同時由多個 goroutine 使用；
+// 不同學年度的學程目錄可各自以 LoadCatalog 載入，再以 New 建立 Checker 並存於同一行程中。
+package engine
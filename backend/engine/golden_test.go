@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// go test ./engine -run TestGolden -update 重新產生 golden 檔與規則涵蓋率報告
+var update = flag.Bool("update", false, "重新產生 golden 檔與規則涵蓋率報告")
+
+// golden 案例：testdata/golden/<學程 ID>/<案例>.json 為成績檔 (課程列表 JSON)，
+// 同目錄的 <案例>.golden.json 為預期的檢核結果
+const goldenDir = "testdata/golden"
+
+// 規則涵蓋率報告 (與 golden 檔一同提交，規則或學程資料變更時可從差異看出受影響的案例)
+var goldenCoveragePath = filepath.Join(goldenDir, "coverage.txt")
+
+func TestGolden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join(goldenDir, "*", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 學程 ID -> 規則 -> 實際改變檢核結果的案例
+	fired := make(map[string]map[string][]string)
+	programsWithFixtures := make(map[string]bool)
+	for _, path := range fixtures {
+		if strings.HasSuffix(path, ".golden.json") {
+			continue
+		}
+		programID := filepath.Base(filepath.Dir(path))
+		name := programID + "/" + strings.TrimSuffix(filepath.Base(path), ".json")
+		programsWithFixtures[programID] = true
+
+		// 每個案例使用各自的追蹤檢核器，同一規則在一個案例中只記錄一次
+		var mu sync.Mutex
+		seen := make(map[string]bool)
+		checker := testChecker.WithRuleTracer(func(programID, rule string) {
+			mu.Lock()
+			defer mu.Unlock()
+			key := programID + "\x00" + rule
+			if seen[key] {
+				return
+			}
+			seen[key] = true
+			if fired[programID] == nil {
+				fired[programID] = make(map[string][]string)
+			}
+			fired[programID][rule] = append(fired[programID][rule], name)
+		})
+
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			transcript, err := checker.ParseTranscript(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := checker.Check(programID, transcript.Courses, transcript.Profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			checkGolden(t, strings.TrimSuffix(path, ".json")+".golden.json", got)
+		})
+	}
+
+	// 追蹤到的規則須列於規則表中 (規則表同時是學程詳細資料顯示的說明)
+	for programID, rules := range fired {
+		known := make(map[string]bool)
+		for _, rule := range programSpecialRules(programID, testChecker.catalog.programs[programID]) {
+			known[rule.id] = true
+		}
+		for rule, cases := range rules {
+			if !known[rule] {
+				t.Errorf("規則 %s/%s (案例 %v) 未列於規則表", programID, rule, cases)
+			}
+		}
+	}
+
+	checkGolden(t, goldenCoveragePath, goldenCoverage(fired, programsWithFixtures))
+}
+
+// 比對 golden 檔；指定 -update 時改為寫入
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("讀取 golden 檔失敗 (新案例請以 -update 產生): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s 與目前結果不符 (確認為預期的變更後以 -update 重新產生):\n%s", path, firstDiff(want, got))
+	}
+}
+
+// 第一個不同的行及其前後文，方便從測試輸出判斷改變的欄位
+func firstDiff(want, got []byte) string {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	i := 0
+	for i < len(wantLines) && i < len(gotLines) && wantLines[i] == gotLines[i] {
+		i++
+	}
+	var b strings.Builder
+	for j := max(i-3, 0); j < i; j++ {
+		fmt.Fprintf(&b, "  %4d   %s\n", j+1, wantLines[j])
+	}
+	for j := i; j < min(i+3, len(wantLines)); j++ {
+		fmt.Fprintf(&b, "- %4d   %s\n", j+1, wantLines[j])
+	}
+	for j := i; j < min(i+3, len(gotLines)); j++ {
+		fmt.Fprintf(&b, "+ %4d   %s\n", j+1, gotLines[j])
+	}
+	return b.String()
+}
+
+// 規則涵蓋率報告：列出每條特殊規則實際改變檢核結果的案例，未被任何案例觸發的規則標示為未涵蓋
+func goldenCoverage(fired map[string]map[string][]string, programsWithFixtures map[string]bool) []byte {
+	programs := testChecker.catalog.programs
+	ids := make([]string, 0, len(programs))
+	for id := range programs {
+		ids = append(ids, id)
+	}
+	for id := range specialRules {
+		if _, ok := programs[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var b strings.Builder
+	b.WriteString("# 特殊規則涵蓋率：各規則實際改變檢核結果的 golden 案例\n")
+	b.WriteString("# 由 go test ./engine -run TestGolden -update 產生，請勿手動修改\n")
+	total, covered := 0, 0
+	for _, id := range ids {
+		program, ok := programs[id]
+		rules := programSpecialRules(id, program)
+		if len(rules) == 0 {
+			continue
+		}
+		b.WriteString("\n" + id)
+		if !ok {
+			b.WriteString(" (目錄中無此學程)")
+		}
+		b.WriteString("\n")
+		seen := make(map[string]bool)
+		for _, rule := range rules {
+			if seen[rule.id] {
+				continue
+			}
+			seen[rule.id] = true
+			total++
+			cases := fired[id][rule.id]
+			if len(cases) == 0 {
+				fmt.Fprintf(&b, "  %-28s (未涵蓋)\n", rule.id)
+				continue
+			}
+			covered++
+			sort.Strings(cases)
+			fmt.Fprintf(&b, "  %-28s %s\n", rule.id, strings.Join(cases, " "))
+		}
+	}
+	fmt.Fprintf(&b, "\n涵蓋 %d / %d 條規則；%d / %d 個學程有 golden 案例\n", covered, total, len(programsWithFixtures), len(programs))
+	return []byte(b.String())
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+)
+
+// --- 學生基本資料 (學制、入學年度、雙主修、輔系、所屬學院) ---
+
+// 學制
+const (
+	DegreeBachelor = "bachelor" // 學士班
+	DegreeMaster   = "master"   // 碩士班 (含在職專班)
+	DegreeDoctoral = "doctoral" // 博士班
+)
+
+// 由成績檔取出的學生資料，供資格限制與依學制、年級而異的規則使用
+type StudentProfile struct {
+	Major          string `json:"major"`
+	DepartmentCode string `json:"departmentCode,omitempty"` // 主修對應的系所代碼 (依 departments_grouped.json)
+	CollegeCode    string `json:"collegeCode,omitempty"`    // 主修所屬學院的分組代碼
+	College        string `json:"college,omitempty"`        // 主修所屬學院
+	DegreeLevel    string `json:"degreeLevel,omitempty"`    // bachelor / master / doctoral
+	EnrollmentYear int    `json:"enrollmentYear,omitempty"` // 入學學年度 (民國年)
+	YearOfStudy    int    `json:"yearOfStudy,omitempty"`    // 年級 (以成績檔中最近的學年計算)
+	DoubleMajor    string `json:"doubleMajor,omitempty"`
+	Minor          string `json:"minor,omitempty"`
+}
+
+// 成績檔中屬於學生資料的欄位 (欄位名稱對照見 transcriptFieldAliases)
+var profileFieldNames = []string{"major", "doubleMajor", "minor", "degreeLevel", "enrollmentYear"}
+
+// 取出欄位中的學生資料 (供各格式解析器使用)
+func profileFields(fields map[string]string) map[string]string {
+	profile := make(map[string]string)
+	for _, name := range profileFieldNames {
+		if v := strings.TrimSpace(fields[name]); v != "" {
+			profile[name] = v
+		}
+	}
+	return profile
+}
+
+// 補上尚未取得的學生資料欄位 (先出現者優先)
+func mergeProfileFields(dst, src map[string]string) {
+	for k, v := range src {
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+}
+
+// 由原始欄位與課程紀錄建立學生資料 (主修依檢核器的系所資料解析)：
+// 未提供的學制由系所名稱推斷，入學年度預設為最早的修課學年
+func (c *Checker) newStudentProfile(fields map[string]string, courses []StudentCourse) StudentProfile {
+	p := StudentProfile{
+		Major:       fields["major"],
+		DoubleMajor: fields["doubleMajor"],
+		Minor:       fields["minor"],
+	}
+	dept, ok := c.departments.Resolve(p.Major)
+	if ok {
+		p.DepartmentCode = dept.Code
+		p.CollegeCode = dept.CollegeCode
+		p.College = dept.College
+	}
+
+	// 學制：成績檔欄位 > 主修名稱中的學制 (例如「金融學系碩士班」) > 系所預設學制
+	p.DegreeLevel = normalizeDegreeLevel(fields["degreeLevel"])
+	if p.DegreeLevel == "" {
+		p.DegreeLevel = degreeLevelOfDepartment(p.Major)
+	}
+	if p.DegreeLevel == "" && ok {
+		p.DegreeLevel = dept.DegreeLevel
+	}
+
+	first, last := 0, 0
+	for _, course := range courses {
+		year, _, _ := strings.Cut(course.Semester, "-")
+		y, err := strconv.Atoi(year)
+		if err != nil || y <= 0 {
+			continue
+		}
+		if first == 0 || y < first {
+			first = y
+		}
+		if y > last {
+			last = y
+		}
+	}
+	if y, err := strconv.Atoi(strings.TrimSpace(fields["enrollmentYear"])); err == nil && y > 0 {
+		p.EnrollmentYear = y
+	} else {
+		p.EnrollmentYear = first
+	}
+	if p.EnrollmentYear > 0 && last >= p.EnrollmentYear {
+		p.YearOfStudy = last - p.EnrollmentYear + 1
+	}
+	return p
+}
+
+// 由系所名稱推斷學制 (無法判斷時回傳空字串)
+func degreeLevelOfDepartment(name string) string {
+	switch {
+	case name == "":
+		return ""
+	case strings.Contains(name, "博士"):
+		return DegreeDoctoral
+	case strings.Contains(name, "碩士"), strings.Contains(name, "研究所"):
+		return DegreeMaster
+	case strings.Contains(name, "學士"), strings.HasSuffix(name, "學系"), strings.HasSuffix(name, "學程"):
+		return DegreeBachelor
+	}
+	return ""
+}
+
+// 成績檔中的學制文字 -> 學制代碼
+func normalizeDegreeLevel(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case s == "":
+		return ""
+	case s == DegreeBachelor || s == "undergraduate" || strings.Contains(s, "學士") || strings.Contains(s, "大學"):
+		return DegreeBachelor
+	case s == DegreeMaster || strings.Contains(s, "碩士"):
+		return DegreeMaster
+	case s == DegreeDoctoral || s == "doctorate" || s == "phd" || strings.Contains(s, "博士"):
+		return DegreeDoctoral
+	}
+	return ""
+}
+
+// 是否為商學院學生 (依主修所屬學院判斷，用於商學院限定或排除商學院的學程)
+func (p StudentProfile) isBusinessStudent() bool {
+	return p.CollegeCode == businessCollegeCode
+}
+
+var degreeLevelLabels = map[string]string{
+	DegreeBachelor: "學士班",
+	DegreeMaster:   "碩士班",
+	DegreeDoctoral: "博士班",
+}
+
+// 報告中顯示的學生資料 (僅列出有值的欄位)
+func (p StudentProfile) Summary() []string {
+	var lines []string
+	add := func(label, value string) {
+		if value != "" {
+			lines = append(lines, label+"："+value)
+		}
+	}
+	add("主修", p.Major)
+	add("學院", p.College)
+	add("學制", degreeLevelLabels[p.DegreeLevel])
+	add("雙主修", p.DoubleMajor)
+	add("輔系", p.Minor)
+	return lines
+}
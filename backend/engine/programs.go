@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// --- 學程索引 (於 LoadCatalog 時建立，供查詢與反查使用) ---
+
+// 課程在某學程中出現的位置
+type CourseRef struct {
+	ProgramID string `json:"programID"`
+	Category  string `json:"category"`
+}
+
+// 學程摘要 (用於列表與搜尋結果)
+type ProgramSummary struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Colleges    []string `json:"colleges"`
+	MinCredits  float64  `json:"minCredits"`
+	Description string   `json:"description"`
+	URL         string   `json:"url"`
+}
+
+// 學程詳細資料 (含認列要求與特殊規則)
+type ProgramDetail struct {
+	ID                      string               `json:"id"`
+	Name                    string               `json:"name"`
+	Type                    string               `json:"type"`
+	Colleges                []string             `json:"colleges"`
+	MinCredits              float64              `json:"minCredits"`
+	Description             string               `json:"description"`
+	URL                     string               `json:"url"`
+	Requirements            []ProgramRequirement `json:"requirements"`
+	GeneralEducationCourses []string             `json:"generalEducationCourses"`
+	Rules                   []string             `json:"rules"`
+}
+
+// 學程索引
+type programIndex struct {
+	colleges   map[string][]string    // 學程 ID -> 所屬學院 (跨院學程會有多個)
+	searchText map[string]string      // 學程 ID -> 小寫的名稱與說明 (全文搜尋用)
+	courses    map[string][]CourseRef // 課程比對鍵 (courseKey) -> 出現的學程與分類
+	sortedIDs  []string
+}
+
+// 依目錄中的學程建立索引
+func buildProgramIndex(c *Catalog) *programIndex {
+	idx := &programIndex{
+		colleges:   make(map[string][]string),
+		searchText: make(map[string]string),
+		courses:    make(map[string][]CourseRef),
+	}
+
+	for college, collegePrograms := range c.byCollege {
+		for id := range collegePrograms {
+			idx.colleges[id] = append(idx.colleges[id], college)
+		}
+	}
+
+	for id, p := range c.programs {
+		sort.Strings(idx.colleges[id])
+		idx.searchText[id] = strings.ToLower(p.Name + "\n" + p.Description)
+		idx.sortedIDs = append(idx.sortedIDs, id)
+
+		addCourse := func(name, category string) {
+			key := c.courseKey(name)
+			if key == "" {
+				return
+			}
+			ref := CourseRef{ProgramID: id, Category: category}
+			// 同一學程同一分類僅記錄一次
+			for _, existing := range idx.courses[key] {
+				if existing == ref {
+					return
+				}
+			}
+			idx.courses[key] = append(idx.courses[key], ref)
+		}
+
+		compiled := c.compiled[id]
+		for _, req := range compiled.requirements {
+			for _, name := range req.Courses {
+				addCourse(name, req.Category)
+			}
+		}
+		for _, name := range compiled.geSorted {
+			addCourse(name, "通識課程")
+		}
+	}
+	sort.Strings(idx.sortedIDs)
+
+	for _, refs := range idx.courses {
+		sort.Slice(refs, func(i, j int) bool {
+			if refs[i].ProgramID != refs[j].ProgramID {
+				return refs[i].ProgramID < refs[j].ProgramID
+			}
+			return refs[i].Category < refs[j].Category
+		})
+	}
+
+	return idx
+}
+
+// 學程查詢條件
+type ProgramQuery struct {
+	Text    string // 名稱或說明的關鍵字 (以空白分隔，須全部符合)
+	Type    string
+	College string
+	Course  string // 須包含此課程
+}
+
+// 依條件搜尋學程，名稱符合者排在前面
+func (c *Catalog) Search(q ProgramQuery) []ProgramSummary {
+	terms := strings.Fields(strings.ToLower(q.Text))
+
+	var candidates []string
+	if q.Course != "" {
+		seen := make(map[string]bool)
+		for _, ref := range c.index.courses[c.courseKey(q.Course)] {
+			if !seen[ref.ProgramID] {
+				seen[ref.ProgramID] = true
+				candidates = append(candidates, ref.ProgramID)
+			}
+		}
+	} else {
+		candidates = c.index.sortedIDs
+	}
+
+	type scored struct {
+		id    string
+		score int
+	}
+	var matches []scored
+	for _, id := range candidates {
+		p := c.programs[id]
+		if q.Type != "" && p.Type != q.Type {
+			continue
+		}
+		if q.College != "" && !slices.Contains(c.index.colleges[id], q.College) {
+			continue
+		}
+
+		score := 0
+		matched := true
+		lowerName := strings.ToLower(p.Name)
+		for _, term := range terms {
+			if !strings.Contains(c.index.searchText[id], term) {
+				matched = false
+				break
+			}
+			if strings.Contains(lowerName, term) {
+				score++
+			}
+		}
+		if matched {
+			matches = append(matches, scored{id, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	results := []ProgramSummary{}
+	for _, m := range matches {
+		results = append(results, c.Summary(m.id))
+	}
+	return results
+}
+
+// 學程摘要 (搜尋結果與清單使用；id 須存在於目錄中)
+func (c *Catalog) Summary(id string) ProgramSummary {
+	p := c.programs[id]
+	return ProgramSummary{
+		ID:          id,
+		Name:        p.Name,
+		Type:        p.Type,
+		Colleges:    slices.Clone(c.index.colleges[id]),
+		MinCredits:  p.MinCredits,
+		Description: p.Description,
+		URL:         p.URL,
+	}
+}
+
+// 學程詳細資料 (回傳內容為複本，修改不影響目錄)；學程不存在時 ok 為 false
+func (c *Catalog) Detail(id string) (detail ProgramDetail, ok bool) {
+	p, ok := c.programs[id]
+	if !ok {
+		return ProgramDetail{}, false
+	}
+	compiled := c.compiled[id]
+
+	reqs := make([]ProgramRequirement, len(compiled.requirements))
+	for i, req := range compiled.requirements {
+		reqs[i] = req
+		reqs[i].Courses = slices.Clone(req.Courses)
+	}
+	return ProgramDetail{
+		ID:                      id,
+		Name:                    p.Name,
+		Type:                    p.Type,
+		Colleges:                slices.Clone(c.index.colleges[id]),
+		MinCredits:              p.MinCredits,
+		Description:             p.Description,
+		URL:                     p.URL,
+		Requirements:            reqs,
+		GeneralEducationCourses: slices.Clone(compiled.geSorted),
+		Rules:                   slices.Clone(compiled.rules),
+	}, true
+}
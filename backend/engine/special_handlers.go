@@ -1,4 +1,4 @@
-package main
+package engine
 
 import (
 	"fmt"
@@ -8,6 +8,7 @@ import (
 )
 
 // preprocessRequirements 階段 1: 處理學程要求的預處理 (名稱解析、特殊學程的課程清單調整)
+// 於載入時由 compileProgram 呼叫一次，檢核時使用編譯後的結果
 func preprocessRequirements(programID string, program Program) ([]ProgramRequirement, map[string]bool, map[string]bool, map[string]string) {
 	// 建立 Requirements 的副本
 	localRequirements := make([]ProgramRequirement, len(program.Requirements))
@@ -25,24 +26,28 @@ func preprocessRequirements(programID string, program Program) ([]ProgramRequire
 	isSoutheastAsianProgram := programID == "southeast_asian_area_studies"
 
 	for i, req := range localRequirements {
-		for j, courseName := range req.Courses {
+		seen := make(map[string]bool)
+		names := localRequirements[i].Courses[:0]
+		for _, courseName := range req.Courses {
+			norm := normalizeCourseName(courseName)
 			// 特殊處理：東南亞區域研究微學程 - 處理 "課程名稱(教師名)" 格式
 			if isSoutheastAsianProgram && strings.Contains(courseName, "(") && strings.HasSuffix(courseName, ")") {
 				start := strings.LastIndex(courseName, "(")
-				realName := strings.TrimSpace(courseName[:start])
 				instructor := courseName[start+1 : len(courseName)-1]
-
-				// 更新副本中的課程名稱為真實名稱
-				localRequirements[i].Courses[j] = realName
+				norm = normalizeCourseName(courseName[:start])
 
 				// 記錄該課程對應的教師
-				norm := normalizeCourseName(realName)
 				courseInstructorMap[norm] = instructor
-				programCourseNamesClean[norm] = true
-			} else {
-				programCourseNamesClean[normalizeCourseName(courseName)] = true
+			}
+			programCourseNamesClean[norm] = true
+
+			// 副本中的課程名稱統一為正規化的名稱，同一分類中重複的課程只保留一次
+			if !seen[norm] {
+				seen[norm] = true
+				names = append(names, norm)
 			}
 		}
+		localRequirements[i].Courses = names
 	}
 
 	// 加入通識課程定義
@@ -72,7 +77,7 @@ func preprocessRequirements(programID string, program Program) ([]ProgramRequire
 }
 
 // filterAndProcessCourses 階段 2: 篩選並處理課程 (含特殊學程的學分上限、群組調整等)
-func filterAndProcessCourses(programID string, rawCourses []StudentCourse, localRequirements *[]ProgramRequirement, programCourseNamesClean map[string]bool, geCourseNames map[string]bool, courseInstructorMap map[string]string) ([]StudentCourse, []StudentCourse) {
+func filterAndProcessCourses(trace RuleTracer, programID string, rawCourses []StudentCourse, localRequirements *[]ProgramRequirement, programCourseNamesClean map[string]bool, geCourseNames map[string]bool, courseInstructorMap map[string]string) ([]StudentCourse, []StudentCourse) {
 	var relevantPassed []StudentCourse
 	var inProgressCourses []StudentCourse
 
@@ -103,6 +108,8 @@ func filterAndProcessCourses(programID string, rawCourses []StudentCourse, local
 				if instructorCounts[instructor] < 2 {
 					instructorCounts[instructor]++
 					filteredByInstructor = append(filteredByInstructor, c)
+				} else {
+					trace.fire(programID, "instructor_limit")
 				}
 			} else {
 				filteredByInstructor = append(filteredByInstructor, c)
@@ -123,6 +130,7 @@ func filterAndProcessCourses(programID string, rawCourses []StudentCourse, local
 		var newInProgress []StudentCourse
 		for _, c := range inProgressCourses {
 			if specialCourses[c.Name] {
+				trace.fire(programID, "in_progress_prerequisites")
 				c.IsPassed = true
 				relevantPassed = append(relevantPassed, c)
 			} else {
@@ -136,13 +144,14 @@ func filterAndProcessCourses(programID string, rawCourses []StudentCourse, local
 	if programID == "patent" {
 		// 1. 處理學分上限 (Capping)
 		type CapGroup struct {
+			Rule    string
 			Courses []string
 			Limit   float64
 		}
 		capGroups := []CapGroup{
-			{[]string{"微積分"}, 2.0},
-			{[]string{"民法概要", "民法總則", "民法債編總論（一）", "民法債編總論（二）"}, 6.0},
-			{[]string{"普通物理學實驗", "普通物理學實驗（一）", "普通物理學實驗（二）"}, 2.0},
+			{"calculus_cap", []string{"微積分"}, 2.0},
+			{"civil_law_cap", []string{"民法概要", "民法總則", "民法債編總論（一）", "民法債編總論（二）"}, 6.0},
+			{"physics_lab_cap", []string{"普通物理學實驗", "普通物理學實驗（一）", "普通物理學實驗（二）"}, 2.0},
 		}
 
 		for _, group := range capGroups {
@@ -160,9 +169,11 @@ func filterAndProcessCourses(programID string, rawCourses []StudentCourse, local
 			currentTotal := 0.0
 			for _, c := range groupCourses {
 				if currentTotal >= group.Limit {
+					trace.fire(programID, group.Rule)
 					c.Credit = 0
 					c.IsCapped = true
 				} else if currentTotal+c.Credit > group.Limit {
+					trace.fire(programID, group.Rule)
 					allowed := group.Limit - currentTotal
 					c.Credit = allowed
 					c.IsCapped = true
@@ -184,11 +195,12 @@ func filterAndProcessCourses(programID string, rawCourses []StudentCourse, local
 
 		if hasCivilLawOverview {
 			var businessReqIndex, lawReqIndex int = -1, -1
+			// 分類名稱可能附有說明 (例如「法學院（民法課程最多採計 6 學分）」)
 			for i, req := range *localRequirements {
-				switch req.Category {
-				case "商學院":
+				switch {
+				case strings.HasPrefix(req.Category, "商學院"):
 					businessReqIndex = i
-				case "法學院":
+				case strings.HasPrefix(req.Category, "法學院"):
 					lawReqIndex = i
 				}
 			}
@@ -221,6 +233,9 @@ func filterAndProcessCourses(programID string, rawCourses []StudentCourse, local
 						newCourses = append(newCourses, c)
 					}
 				}
+				if len(newCourses) < len((*localRequirements)[targetToRemoveIndex].Courses) {
+					trace.fire(programID, "civil_law_overview_category")
+				}
 				(*localRequirements)[targetToRemoveIndex].Courses = newCourses
 			}
 		}
@@ -243,6 +258,9 @@ func filterAndProcessCourses(programID string, rawCourses []StudentCourse, local
 				}
 			}
 			if isCompIntro {
+				if foundCompIntro {
+					trace.fire(programID, "computing_intro_choose_one")
+				}
 				if !foundCompIntro || c.Credit > bestCompIntro.Credit {
 					bestCompIntro = c
 					foundCompIntro = true
@@ -304,6 +322,9 @@ func filterAndProcessCourses(programID string, rawCourses []StudentCourse, local
 				}
 			}
 
+			if len(passedOverlap) > 0 {
+				trace.fire(programID, "overlap_assignment")
+			}
 			assignedToA := []string{}
 			assignedToC := []string{}
 			currentA := passedPureA
@@ -349,6 +370,7 @@ func filterAndProcessCourses(programID string, rawCourses []StudentCourse, local
 		}
 	}
 	if len(gePassed) > 1 {
+		trace.fire(programID, "general_education_limit")
 		sort.Slice(gePassed, func(i, j int) bool {
 			return gePassed[i].Credit > gePassed[j].Credit
 		})
@@ -360,7 +382,7 @@ func filterAndProcessCourses(programID string, rawCourses []StudentCourse, local
 }
 
 // processManagementAccounting 特殊處理：管理會計專業學程的計算邏輯
-func processManagementAccounting(program Program, completedCourses []StudentCourse) ([]CategoryResult, bool, float64) {
+func processManagementAccounting(trace RuleTracer, program *compiledProgram, completedCourses []StudentCourse) ([]CategoryResult, bool, float64) {
 	econCredits := 0.0
 	totalPassedCredits := 0.0
 	for _, c := range completedCourses {
@@ -371,6 +393,9 @@ func processManagementAccounting(program Program, completedCourses []StudentCour
 	}
 
 	if econCredits < 6.0 {
+		if econCredits > 0 {
+			trace.fire(program.id, "economics_minimum")
+		}
 		totalPassedCredits -= econCredits
 		var newCompleted []StudentCourse
 		for _, c := range completedCourses {
@@ -381,7 +406,7 @@ func processManagementAccounting(program Program, completedCourses []StudentCour
 		completedCourses = newCompleted
 	}
 
-	isMet := totalPassedCredits >= program.MinCredits
+	isMet := totalPassedCredits >= program.minCredits
 
 	uniquePassedCourseNames := make(map[string]bool)
 	for _, c := range completedCourses {
@@ -390,9 +415,9 @@ func processManagementAccounting(program Program, completedCourses []StudentCour
 	passedCount := len(uniquePassedCourseNames)
 
 	results := []CategoryResult{{
-		Category:        program.Requirements[0].Category,
+		Category:        program.requirements[0].Category,
 		RequiredCount:   0,
-		RequiredCredits: program.MinCredits,
+		RequiredCredits: program.minCredits,
 		PassedCount:     passedCount,
 		PassedCredits:   totalPassedCredits,
 		IsMet:           isMet,
@@ -403,7 +428,7 @@ func processManagementAccounting(program Program, completedCourses []StudentCour
 }
 
 // postprocessResults 階段 3: 處理計算後的特殊規則 (跨群檢核、平均成績、系所限制等)
-func postprocessResults(programID string, program Program, studentMajor string, categoryResults []CategoryResult, effectiveTotalCredits float64) ([]CategoryResult, bool, string, bool, string, bool, string, float64) {
+func postprocessResults(trace RuleTracer, programID string, program *compiledProgram, profile StudentProfile, categoryResults []CategoryResult, effectiveTotalCredits float64) ([]CategoryResult, bool, string, bool, string, bool, string, float64) {
 	allCategoriesMet := true
 	for _, res := range categoryResults {
 		if !res.IsMet {
@@ -431,6 +456,7 @@ func postprocessResults(programID string, program Program, studentMajor string,
 			isMet := total >= 3
 			msg := ""
 			if !isMet {
+				trace.fire(programID, "group_ab_total")
 				allCategoriesMet = false
 				msg = "群A與群B合計須至少修習 3 門"
 			}
@@ -461,7 +487,7 @@ func postprocessResults(programID string, program Program, studentMajor string,
 	}
 
 	// 特殊處理：跨領域精準健康學分學程
-	if program.Name == "跨領域精準健康學分學程" {
+	if program.name == "跨領域精準健康學分學程" {
 		targetGroups := []string{"群A", "群B", "群C", "群D"}
 		metGroups := 0
 		for _, group := range targetGroups {
@@ -476,6 +502,7 @@ func postprocessResults(programID string, program Program, studentMajor string,
 		isMet := metGroups >= 2
 		msg := ""
 		if !isMet {
+			trace.fire(programID, "cross_group")
 			allCategoriesMet = false
 			msg = "須於群A至群D中至少修習兩群課程"
 		}
@@ -542,6 +569,7 @@ func postprocessResults(programID string, program Program, studentMajor string,
 				}
 
 				if !hasViet && !hasIndo && !hasThai {
+					trace.fire(programID, "language_pair")
 					categoryResults[i].IsMet = false
 					allCategoriesMet = false
 					categoryResults[i].LimitExceeded = true
@@ -574,6 +602,7 @@ func postprocessResults(programID string, program Program, studentMajor string,
 			isMet := count >= 2
 			msg := ""
 			if !isMet {
+				trace.fire(programID, "procedure_total")
 				allCategoriesMet = false
 				msg = "程序課程三類（管理類、勞工關係類、行為類）總共須至少修習 2 門"
 			}
@@ -621,6 +650,7 @@ func postprocessResults(programID string, program Program, studentMajor string,
 				}
 
 				if !hasBehavioral {
+					trace.fire(programID, "org_behavior_prerequisite")
 					newPassed := []StudentCourse{}
 					for _, c := range categoryResults[reqCatIndex].PassedCourses {
 						if c.Name != "組織行為專題研究" {
@@ -673,6 +703,7 @@ func postprocessResults(programID string, program Program, studentMajor string,
 							newPassed = append(newPassed, c)
 							newPassedCredits += c.Credit
 						} else {
+							trace.fire(programID, "same_group_choose_one")
 							effectiveTotalCredits -= c.Credit
 						}
 					} else {
@@ -706,23 +737,10 @@ func postprocessResults(programID string, program Program, studentMajor string,
 
 		totalScoreCredit := 0.0
 		totalCreditForAvg := 0.0
-		uniqueCoursesForAvg := make(map[string]bool)
-
-		for _, res := range categoryResults {
-			if strings.Contains(res.Category, "先修") {
-				continue
-			}
-			for _, c := range res.PassedCourses {
-				key := c.Name + "-" + c.Semester
-				if !uniqueCoursesForAvg[key] {
-					uniqueCoursesForAvg[key] = true
-					s, err := strconv.ParseFloat(c.Score, 64)
-					if err == nil {
-						totalScoreCredit += s * c.Credit
-						totalCreditForAvg += c.Credit
-					}
-				}
-			}
+		for _, c := range avgScoreCourses(categoryResults) {
+			s, _ := strconv.ParseFloat(c.Score, 64)
+			totalScoreCredit += s * c.Credit
+			totalCreditForAvg += c.Credit
 		}
 
 		avg := 0.0
@@ -731,42 +749,59 @@ func postprocessResults(programID string, program Program, studentMajor string,
 		}
 		avgScoreStr = fmt.Sprintf("%.2f", avg)
 		avgScoreMet = avg >= threshold
+		if !avgScoreMet {
+			trace.fire(programID, "average_score")
+		}
 	}
 
 	restrictionMessage := ""
-	// 特殊處理：外語專長商管學分學程 - 商學院學生不得修習
-	if programID == "foreign_language_student_business_primer" && businessMajors[studentMajor] {
+	// 資格限制 (例如限商學院學生)：不符合者視為未完成
+	if !program.eligible(profile) {
+		trace.fire(programID, "eligibility")
 		allCategoriesMet = false
-		restrictionMessage = "本學程限定非商學院學生修習（商學院學生無法申請）"
-	}
-	// 特殊處理：管理會計專業學程 - 非商學院學生不得修習
-	if programID == "management_accounting" && !businessMajors[studentMajor] {
-		allCategoriesMet = false
-		restrictionMessage = "本學程限定商學院學生修習（非商學院學生無法申請）"
+		restrictionMessage = program.eligibility.message
 	}
 
 	return categoryResults, allCategoriesMet, restrictionMessage, avgScoreRequired, avgScoreStr, avgScoreMet, avgScoreThreshold, effectiveTotalCredits
 }
 
+// 學程的資格限制 (於編譯時套用至 compiledProgram.eligibility)
+var eligibilityRules = map[string]eligibilityRule{
+	// 外語專長商管學分學程 - 商學院學生不得修習
+	"foreign_language_student_business_primer": {
+		allow:   func(p StudentProfile) bool { return !p.isBusinessStudent() },
+		message: "本學程限定非商學院學生修習（商學院學生無法申請）",
+	},
+	// 管理會計專業學程 - 非商學院學生不得修習
+	"CIMA": {
+		allow:   func(p StudentProfile) bool { return p.isBusinessStudent() },
+		message: "本學程限定商學院學生修習（非商學院學生無法申請）",
+	},
+}
+
+// 學生是否符合學程的資格限制
+func (p *compiledProgram) eligible(profile StudentProfile) bool {
+	return p.eligibility == nil || p.eligibility.allow(profile)
+}
+
 // processStandardRequirements 處理一般學程的分類要求計算 (核心迴圈邏輯)
-func processStandardRequirements(localRequirements []ProgramRequirement, completedCourses []StudentCourse) ([]CategoryResult, float64) {
+// categories 為 localRequirements 的課程名稱 -> 分類索引
+func processStandardRequirements(localRequirements []ProgramRequirement, completedCourses []StudentCourse, categories categoryIndex) ([]CategoryResult, float64) {
 	var categoryResults []CategoryResult
 	effectiveTotalCredits := 0.0
 
-	for _, req := range localRequirements {
+	// 每門已通過課程所屬的分類 (只查詢一次)
+	courseCategories := make([]categorySet, len(completedCourses))
+	for j, c := range completedCourses {
+		courseCategories[j] = categories[c.Name]
+	}
+
+	for i, req := range localRequirements {
 		passedInThisCategory := []StudentCourse{} // 該分類下已通過的課程紀錄
 
 		// 找出該類別已通過課程
-		for _, c := range completedCourses {
-			// 檢查該課程是否在當前分類的要求列表中
-			isMatch := false
-			for _, reqCourseName := range req.Courses {
-				if c.Name == reqCourseName {
-					isMatch = true
-					break
-				}
-			}
-			if isMatch {
+		for j, c := range completedCourses {
+			if courseCategories[j]&(1<<i) != 0 {
 				passedInThisCategory = append(passedInThisCategory, c)
 			}
 		}
@@ -872,3 +907,85 @@ func processStandardRequirements(localRequirements []ProgramRequirement, complet
 
 	return categoryResults, effectiveTotalCredits
 }
+
+// 特殊學程規則 (對應本檔案中依學程 ID 處理的邏輯)
+type specialRule struct {
+	id          string // 規則識別碼 (RuleTracer 收到的 rule)
+	description string
+}
+
+// 通識課程全域限修一門 (學程定義檔列有通識課程者皆適用)
+var generalEducationRule = specialRule{"general_education_limit", "通識課程認列以一門為限（採計學分最高者）"}
+
+var specialRules = map[string][]specialRule{
+	"southeast_asian_area_studies": {
+		{"instructor_limit", "同一名老師開設之課程至多認列兩門"},
+	},
+	"CFA": {
+		{"in_progress_prerequisites", "「中級會計學（二）」、「投資學」、「商事法」、「民法概要」修習中亦視為已修"},
+		{"average_score", "認列學分之平均成績須達 80 分（不含先修課程）"},
+	},
+	"patent": {
+		{"calculus_cap", "「微積分」至多認列 2 學分"},
+		{"civil_law_cap", "民法課程（民法概要、民法總則、民法債編總論）至多認列 6 學分"},
+		{"physics_lab_cap", "普通物理學實驗至多認列 2 學分"},
+		{"civil_law_overview_category", "「民法概要」依是否修習其他商學院課程，認列於商學院或法學院分類"},
+	},
+	"fintech": {
+		{"computing_intro_choose_one", "計算機概論、計算機程式設計、計算機程式僅能擇一門認列"},
+		{"overlap_assignment", "群A與群B重疊之程式課程優先認列於群A，群A與群B合計達 3 門後改認列於選修C"},
+		{"group_ab_total", "群A與群B合計須至少修習 3 門"},
+	},
+	"interdisciplinary_precision_health": {
+		{"cross_group", "須於群A至群D中至少修習兩群課程"},
+	},
+	"southeast_asia_culture_religion_interdisciplinary": {
+		{"language_pair", "語言領域須修畢同一語言之第一學期及第二學期課程"},
+	},
+	"human_resource_management_undergraduate": {
+		{"procedure_total", "程序課程三類（管理類、勞工關係類、行為類）總共須至少修習 2 門"},
+	},
+	"human_resource_management_master": {
+		{"procedure_total", "程序課程三類（管理類、勞工關係類、行為類）總共須至少修習 2 門"},
+		{"org_behavior_prerequisite", "修習「組織行為專題研究」須另修習至少一門行為類程序課程始得認列"},
+	},
+	"marketing_undergraduate": {
+		{"same_group_choose_one", "同性質選修課程（如公共關係、服務行銷、多變量分析、財務行銷、品牌行銷）擇一認列"},
+		{"average_score", "認列學分之平均成績須達 80 分（不含先修課程）"},
+	},
+	"marketing_master": {
+		{"same_group_choose_one", "同性質選修課程（如公共關係、服務行銷、多變量分析、財務行銷、品牌行銷）擇一認列"},
+		{"average_score", "認列學分之平均成績須達 80 分（不含先修課程）"},
+	},
+	"real_property_financial_management": {
+		{"average_score", "認列學分之平均成績須達 70 分（不含先修課程）"},
+	},
+	"foreign_language_student_business_primer": {
+		{"eligibility", "本學程限定非商學院學生修習"},
+	},
+	"CIMA": {
+		{"economics_minimum", "「經濟學」須修滿 6 學分始得認列"},
+		{"eligibility", "本學程限定商學院學生修習"},
+	},
+	"modern_society_body_gender": {
+		{generalEducationRule.id, "指定通識課程（如「自我、身體、文化」）可認列，以一門為限"},
+	},
+}
+
+// programSpecialRules 回傳學程適用的特殊規則 (同一規則可能有多條說明)
+func programSpecialRules(programID string, program Program) []specialRule {
+	rules := []specialRule{}
+	if len(program.GeneralEducationCourses) > 0 {
+		rules = append(rules, generalEducationRule)
+	}
+	return append(rules, specialRules[programID]...)
+}
+
+// programRules 回傳學程適用的特殊規則說明
+func programRules(programID string, program Program) []string {
+	rules := []string{}
+	for _, rule := range programSpecialRules(programID, program) {
+		rules = append(rules, rule.description)
+	}
+	return rules
+}
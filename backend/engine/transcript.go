@@ -0,0 +1,493 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// --- 成績檔解析 (依內容自動判斷格式) ---
+
+// 成績檔格式：Detect 判斷內容是否屬於此格式，Parse 取出原始課程列與學生資料
+type transcriptParser struct {
+	Name   string
+	Detect func(data []byte) bool
+	Parse  func(data []byte) (rawTranscript, error)
+	// 成績空白是否視為修習中 (手動輸入的格式)；全人系統以「成績未到或無成績」表示修習中
+	BlankScoreInProgress bool
+}
+
+// 成績檔格式登錄表 (依序判斷，先符合者優先)
+type transcriptRegistry []transcriptParser
+
+// 建立格式登錄表；全人系統匯出檔依 schemas 中的結構版本比對
+func newTranscriptRegistry(schemas []inccuSchema) transcriptRegistry {
+	inccu := inccuFormat(schemas)
+	return transcriptRegistry{
+		{Name: "inccu", Detect: inccu.detect, Parse: inccu.parse},
+		{Name: "json", Detect: isCanonicalTranscript, Parse: parseCanonicalTranscript, BlankScoreInProgress: true},
+		{Name: "csv", Detect: isCSVTranscript, Parse: parseCSVTranscript, BlankScoreInProgress: true},
+	}
+}
+
+// 預設的格式登錄表 (含所有已知的全人系統結構版本；建立後不再修改，由各檢核器共用)
+var defaultTranscriptRegistry = newTranscriptRegistry(inccuSchemas)
+
+// 格式解析器的輸出
+type rawTranscript struct {
+	Format  string            // 格式名稱；全人系統匯出檔另含結構版本 (例如 inccu/v1)
+	Profile map[string]string // 學生資料欄位 (見 profileFieldNames)
+	Rows    []transcriptRow
+}
+
+// 解析完成的成績檔
+type parsedTranscript struct {
+	Format        string
+	ProfileFields map[string]string // 學生資料欄位 (由 Checker 依系所資料轉為 StudentProfile)
+	Courses       []StudentCourse
+	Diagnostics   []TranscriptDiagnostic
+}
+
+// 尚未轉換的課程列 (欄位皆為原始字串)
+type transcriptRow struct {
+	Row      int // 第幾筆紀錄 (CSV 為行號)
+	Name     string
+	Credit   string
+	Score    string
+	Year     string
+	Semester string
+}
+
+// 逐列檢查的診斷代碼
+const (
+	diagEmptyName     = "empty_name"
+	diagInvalidCredit = "invalid_credit"
+	diagUnknownScore  = "unknown_score"
+	diagDuplicateRow  = "duplicate_row"
+)
+
+// 解析時的逐列警告 (不影響其他課程的解析)
+type TranscriptDiagnostic struct {
+	File    string `json:"file,omitempty"`
+	Row     int    `json:"row"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// 非數字但可辨識的成績
+var knownTextScores = map[string]bool{
+	"成績未到或無成績": true,
+	"通過":       true,
+	"不通過":      true,
+	"抵免":       true,
+	"免修":       true,
+	"停修":       true,
+}
+
+// 成績檔解析失敗的主要原因 (可用 errors.Is 判斷；其餘為檔案內容損毀等解析錯誤)
+var (
+	ErrUnknownFormat = errors.New("無法辨識的成績檔格式 (支援全人系統匯出的 JSON、課程列表 JSON 與 CSV)")
+	ErrUnknownSchema = errors.New("全人系統匯出檔的結構與已知版本不符，學校可能已更改匯出格式")
+	ErrNoCourses     = errors.New("檔案解析成功，但未找到有效的課程紀錄")
+)
+
+// 全人系統匯出檔的結構不符任何已知版本 (errors.Is(err, ErrUnknownSchema) 成立)
+type UnknownSchemaError struct {
+	Shape string // 區段的欄位結構 (僅鍵名，不含資料內容)，用於追查學校更改的匯出格式
+}
+
+func (e *UnknownSchemaError) Error() string {
+	return fmt.Sprintf("%s (結構: %s)", ErrUnknownSchema, e.Shape)
+}
+
+func (e *UnknownSchemaError) Unwrap() error {
+	return ErrUnknownSchema
+}
+
+// 解析單一成績檔 (格式自動偵測)，並依檢核器的系所資料建立學生資料
+func (c *Checker) ParseTranscript(data []byte) (*Transcript, error) {
+	return c.MergeTranscripts([]TranscriptFile{{Data: data}})
+}
+
+// 解析成績檔，並回傳偵測到的格式與逐列的診斷訊息
+func (reg transcriptRegistry) parse(data []byte) (parsedTranscript, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	for _, p := range reg {
+		if !p.Detect(data) {
+			continue
+		}
+		raw, err := p.Parse(data)
+		if err != nil {
+			return parsedTranscript{}, err
+		}
+		if raw.Format == "" {
+			raw.Format = p.Name
+		}
+
+		courses, diagnostics := buildCourses(raw.Rows, p.BlankScoreInProgress)
+		t := parsedTranscript{
+			Format:        raw.Format,
+			ProfileFields: raw.Profile,
+			Courses:       courses,
+			Diagnostics:   diagnostics,
+		}
+		if len(courses) == 0 {
+			return t, ErrNoCourses
+		}
+		return t, nil
+	}
+	return parsedTranscript{}, ErrUnknownFormat
+}
+
+// 將原始課程列轉為課程紀錄：略過沒有名稱的列，其餘問題僅記錄警告並保留該列
+func buildCourses(rows []transcriptRow, blankScoreInProgress bool) ([]StudentCourse, []TranscriptDiagnostic) {
+	courses := []StudentCourse{}
+	var diagnostics []TranscriptDiagnostic
+	warn := func(row int, code, format string, args ...any) {
+		diagnostics = append(diagnostics, TranscriptDiagnostic{Row: row, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+	seen := make(map[string]int)
+
+	for _, row := range rows {
+		name := strings.TrimSpace(row.Name)
+		if name == "" {
+			warn(row.Row, diagEmptyName, "課程名稱空白，已略過此列")
+			continue
+		}
+
+		creditStr := strings.TrimSpace(row.Credit)
+		credit, err := strconv.ParseFloat(creditStr, 64)
+		if err != nil || credit < 0 {
+			warn(row.Row, diagInvalidCredit, "%s 的學分 %q 無法解析，以 0 學分計", name, creditStr)
+			credit = 0
+		}
+
+		score := strings.TrimSpace(row.Score)
+		if score == "" && blankScoreInProgress {
+			score = "成績未到或無成績"
+		}
+		if _, err := strconv.ParseFloat(score, 64); err != nil && !knownTextScores[score] {
+			if score == "" {
+				warn(row.Row, diagUnknownScore, "%s 沒有成績，視為未通過", name)
+			} else {
+				warn(row.Row, diagUnknownScore, "%s 的成績 %q 格式無法辨識，視為未通過", name, score)
+			}
+		}
+
+		semester := semesterString(row.Year, row.Semester)
+		key := name + "|" + semester + "|" + score
+		// 完全相同的紀錄只保留第一筆，避免學分重複採計
+		if first, ok := seen[key]; ok {
+			warn(row.Row, diagDuplicateRow, "%s (%s) 與第 %d 筆紀錄完全相同，已略過此列，請確認是否重複匯出", name, semester, first)
+			continue
+		}
+		seen[key] = row.Row
+
+		courses = append(courses, StudentCourse{
+			Name:         name,
+			Credit:       credit,
+			Score:        score,
+			IsPassed:     isPassed(score),
+			IsInProgress: isInProgress(score),
+			Semester:     semester,
+		})
+	}
+	return courses, diagnostics
+}
+
+// 組合學期字串 (例如 112-1)；學年留空時沿用學期欄位的內容
+func semesterString(year, semester string) string {
+	year, semester = strings.TrimSpace(year), strings.TrimSpace(semester)
+	if year == "" {
+		return semester
+	}
+	return year + "-" + semester
+}
+
+// --- 欄位名稱對照 (各版本匯出檔與手動輸入格式的欄位名稱不盡相同) ---
+
+// 正規化後的欄位名稱 -> 欄位
+var transcriptFieldAliases = map[string]string{
+	"coursename":     "name",
+	"name":           "name",
+	"course":         "name",
+	"課程":             "name",
+	"課程名稱":           "name",
+	"科目名稱":           "name",
+	"credit":         "credit",
+	"credits":        "credit",
+	"學分":             "credit",
+	"學分數":            "credit",
+	"score":          "score",
+	"grade":          "score",
+	"finalscore":     "score",
+	"成績":             "score",
+	"academicyear":   "year",
+	"year":           "year",
+	"學年":             "year",
+	"學年度":            "year",
+	"semester":       "semester",
+	"term":           "semester",
+	"學期":             "semester",
+	"registermajor":  "major",
+	"major":          "major",
+	"主修":             "major",
+	"系所":             "major",
+	"doublemajor":    "doubleMajor",
+	"雙主修":            "doubleMajor",
+	"minor":          "minor",
+	"輔系":             "minor",
+	"degree":         "degreeLevel",
+	"degreelevel":    "degreeLevel",
+	"學制":             "degreeLevel",
+	"enrollmentyear": "enrollmentYear",
+	"entryyear":      "enrollmentYear",
+	"入學年度":           "enrollmentYear",
+	"入學學年度":          "enrollmentYear",
+}
+
+// 欄位名稱正規化：不分大小寫，忽略空白、底線與連字號
+func normalizeFieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '_' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// 依欄位名稱對照取出 JSON 物件中的欄位 (字串或數字皆可)
+func jsonRecordFields(record map[string]json.RawMessage) map[string]string {
+	fields := make(map[string]string)
+	for key, raw := range record {
+		field, ok := transcriptFieldAliases[normalizeFieldName(key)]
+		if !ok {
+			continue
+		}
+		if _, dup := fields[field]; dup {
+			continue
+		}
+		fields[field] = jsonScalar(raw)
+	}
+	return fields
+}
+
+// JSON 字串或數字轉為字串，其他型別回傳空字串
+func jsonScalar(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
+
+func rowFromFields(row int, fields map[string]string) transcriptRow {
+	return transcriptRow{
+		Row:      row,
+		Name:     fields["name"],
+		Credit:   fields["credit"],
+		Score:    fields["score"],
+		Year:     fields["year"],
+		Semester: fields["semester"],
+	}
+}
+
+// --- 課程列表 JSON：{"major": "...", "courses": [{"name", "credit", "score", "year", "semester"}]} ---
+// 頂層亦可包含 doubleMajor、minor、degreeLevel、enrollmentYear 等學生資料欄位
+
+func isCanonicalTranscript(data []byte) bool {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return false
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return false
+	}
+	_, ok := probe["courses"]
+	return ok
+}
+
+func parseCanonicalTranscript(data []byte) (rawTranscript, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return rawTranscript{}, fmt.Errorf("解析課程列表 JSON 失敗: %w", err)
+	}
+	var courses []map[string]json.RawMessage
+	if err := json.Unmarshal(top["courses"], &courses); err != nil {
+		return rawTranscript{}, fmt.Errorf("解析課程列表 JSON 失敗: %w", err)
+	}
+
+	var rows []transcriptRow
+	for i, c := range courses {
+		rows = append(rows, rowFromFields(i+1, jsonRecordFields(c)))
+	}
+	return rawTranscript{Profile: profileFields(jsonRecordFields(top)), Rows: rows}, nil
+}
+
+// --- CSV：標題列需含課程名稱與學分，其餘欄位 (成績、學年、學期、主修、雙主修等) 可省略 ---
+
+// 讀取 CSV 標題列，回傳欄位 -> 欄位索引
+func csvTranscriptHeader(data []byte) (map[string]int, [][]string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("CSV 檔案是空的")
+	}
+	columns := make(map[string]int)
+	for i, h := range records[0] {
+		if field, ok := transcriptFieldAliases[normalizeFieldName(h)]; ok {
+			if _, dup := columns[field]; !dup {
+				columns[field] = i
+			}
+		}
+	}
+	return columns, records[1:], nil
+}
+
+func isCSVTranscript(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")) {
+		return false
+	}
+	columns, _, err := csvTranscriptHeader(data)
+	if err != nil {
+		return false
+	}
+	_, hasName := columns["name"]
+	_, hasCredit := columns["credit"]
+	return hasName && hasCredit
+}
+
+func parseCSVTranscript(data []byte) (rawTranscript, error) {
+	columns, records, err := csvTranscriptHeader(data)
+	if err != nil {
+		return rawTranscript{}, fmt.Errorf("解析 CSV 失敗: %w", err)
+	}
+
+	var rows []transcriptRow
+	profile := make(map[string]string)
+	for i, record := range records {
+		fields := make(map[string]string)
+		blank := true
+		for field, col := range columns {
+			if col < len(record) {
+				fields[field] = strings.TrimSpace(record[col])
+				blank = blank && fields[field] == ""
+			}
+		}
+		if blank {
+			continue // 略過空白列
+		}
+		mergeProfileFields(profile, profileFields(fields))
+		rows = append(rows, rowFromFields(i+2, fields))
+	}
+	return rawTranscript{Profile: profile, Rows: rows}, nil
+}
+
+// --- 合併多個成績檔 (例如學士班與碩士班分別匯出) ---
+
+// 合併後的成績資料
+type Transcript struct {
+	Major     string           `json:"major"`
+	Profile   StudentProfile   `json:"profile"`
+	Files     []string         `json:"files"`
+	Formats   []string         `json:"formats"` // 各檔案偵測到的格式 (例如 inccu/v1、csv)
+	Courses   []StudentCourse  `json:"courses"`
+	Conflicts []CourseConflict `json:"conflicts"`
+	// 逐列的解析警告 (學分無法解析、成績格式不明、重複列等)
+	Diagnostics []TranscriptDiagnostic `json:"diagnostics"`
+}
+
+// 同一學期的同一門課在不同檔案中成績不一致 (合併時保留最先出現的紀錄)
+type CourseConflict struct {
+	Name     string   `json:"name"`
+	Semester string   `json:"semester"`
+	Scores   []string `json:"scores"` // 依檔案順序
+	Files    []string `json:"files"`
+}
+
+type TranscriptFile struct {
+	Name string
+	Data []byte
+}
+
+// 依序合併多個成績檔：不同檔案中課程名稱與學期相同的紀錄視為同一筆，
+// 成績相同者去除重複，已有成績者取代修習中的紀錄，成績不同者列為衝突
+func (c *Checker) MergeTranscripts(files []TranscriptFile) (*Transcript, error) {
+	t := &Transcript{Courses: []StudentCourse{}, Conflicts: []CourseConflict{}, Diagnostics: []TranscriptDiagnostic{}}
+	index := make(map[string]int)     // 課程鍵 -> t.Courses 中的位置 (僅含先前檔案的紀錄)
+	source := make(map[string]string) // 課程鍵 -> 紀錄來源檔案
+	conflictIndex := make(map[string]int)
+	fields := make(map[string]string) // 學生資料欄位 (先上傳的檔案優先)
+
+	for _, f := range files {
+		parsed, err := c.transcripts.parse(f.Data)
+		for _, d := range parsed.Diagnostics {
+			d.File = f.Name
+			t.Diagnostics = append(t.Diagnostics, d)
+		}
+		if err != nil {
+			if len(files) == 1 {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		t.Files = append(t.Files, f.Name)
+		t.Formats = append(t.Formats, parsed.Format)
+		mergeProfileFields(fields, parsed.ProfileFields)
+
+		// 同一檔案內的同名課程 (例如同學期修兩門體育) 皆保留
+		added := make(map[string]int)
+		for _, course := range parsed.Courses {
+			key := c.catalog.courseKey(course.Name) + "|" + course.Semester
+			if i, ok := index[key]; ok {
+				existing := t.Courses[i]
+				switch {
+				case existing.Score == course.Score || course.IsInProgress:
+				case existing.IsInProgress:
+					t.Courses[i] = course
+					source[key] = f.Name
+				default:
+					ci, ok := conflictIndex[key]
+					if !ok {
+						ci = len(t.Conflicts)
+						conflictIndex[key] = ci
+						t.Conflicts = append(t.Conflicts, CourseConflict{
+							Name:     existing.Name,
+							Semester: existing.Semester,
+							Scores:   []string{existing.Score},
+							Files:    []string{source[key]},
+						})
+					}
+					t.Conflicts[ci].Scores = append(t.Conflicts[ci].Scores, course.Score)
+					t.Conflicts[ci].Files = append(t.Conflicts[ci].Files, f.Name)
+				}
+				continue
+			}
+			if _, ok := added[key]; !ok {
+				added[key] = len(t.Courses)
+			}
+			t.Courses = append(t.Courses, course)
+		}
+		for key, i := range added {
+			index[key] = i
+			source[key] = f.Name
+		}
+	}
+	t.Profile = c.newStudentProfile(fields, t.Courses)
+	t.Major = t.Profile.Major
+	return t, nil
+}
@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// --- 全人系統匯出格式 (依結構版本以轉接設定解析) ---
+
+// 匯出檔的結構版本：各欄位為 JSON 物件中的鍵名 (比對方式同記錄欄位，見 jsonField)
+type inccuSchema struct {
+	Version  string
+	Section  string   // 頂層項目中的區段 (例如 課業學習)
+	List     string   // 區段中依學年分組的列表；空字串表示課程紀錄直接位於區段中
+	ListYear string   // 分組上的學年欄位 (課程紀錄缺少學年時使用)
+	Records  string   // 課程紀錄陣列
+	Profile  []string // 學生資料物件在區段中的路徑
+}
+
+// 已知的結構版本 (依序比對；學校更改匯出格式時在此新增版本即可，預設的格式登錄表以此建立)
+var inccuSchemas = []inccuSchema{
+	{
+		// [{"課業學習": {"aboutMe": {...}, "gradeRecordList": [{"AcademicYear", "GradeRecords": [...]}]}}]
+		Version:  "v1",
+		Section:  "課業學習",
+		List:     "gradeRecordList",
+		ListYear: "AcademicYear",
+		Records:  "GradeRecords",
+		Profile:  []string{"aboutMe"},
+	},
+}
+
+// 依序比對的結構版本 (由格式登錄表持有)
+type inccuFormat []inccuSchema
+
+// 頂層為陣列且至少一個項目含有已知區段
+func (schemas inccuFormat) detect(data []byte) bool {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return false
+	}
+	var entries []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		for _, schema := range schemas {
+			if _, ok := jsonField(entry, schema.Section); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (schemas inccuFormat) parse(data []byte) (rawTranscript, error) {
+	var entries []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return rawTranscript{}, fmt.Errorf("解析頂層 JSON 結構失敗: %w", err)
+	}
+
+	// 匯出檔可能包含多個學籍 (例如學士班與碩士班)，依序讀取
+	t := rawTranscript{Profile: make(map[string]string)}
+	var versions []string
+	for _, entry := range entries {
+		schema, section, found := schemas.match(entry)
+		if !found {
+			continue
+		}
+		if schema == nil {
+			// 有區段但結構不符任何已知版本：回報欄位名稱 (不含內容) 以便追查
+			return rawTranscript{}, &UnknownSchemaError{Shape: jsonShape(section)}
+		}
+		if !slices.Contains(versions, schema.Version) {
+			versions = append(versions, schema.Version)
+		}
+		schema.extract(section, &t)
+	}
+	if len(t.Rows) == 0 {
+		return rawTranscript{}, fmt.Errorf("JSON 結構不符預期或未找到課程紀錄")
+	}
+	t.Format = "inccu/" + strings.Join(versions, "+")
+	return t, nil
+}
+
+// 依序比對已知版本；found 表示項目含有任一版本的區段，schema 為 nil 表示無版本符合
+func (schemas inccuFormat) match(entry map[string]json.RawMessage) (schema *inccuSchema, section map[string]json.RawMessage, found bool) {
+	for i := range schemas {
+		s := &schemas[i]
+		raw, ok := jsonField(entry, s.Section)
+		if !ok {
+			continue
+		}
+		found = true
+		var sec map[string]json.RawMessage
+		if json.Unmarshal(raw, &sec) != nil {
+			continue
+		}
+		if s.matches(sec) {
+			return s, sec, true
+		}
+		if section == nil {
+			section = sec
+		}
+	}
+	return nil, section, found
+}
+
+// 區段是否具有此版本的必要欄位 (分組列表或課程紀錄須為陣列)
+func (s inccuSchema) matches(section map[string]json.RawMessage) bool {
+	key := s.Records
+	if s.List != "" {
+		key = s.List
+	}
+	raw, ok := jsonField(section, key)
+	if !ok {
+		return false
+	}
+	var list []map[string]json.RawMessage
+	if json.Unmarshal(raw, &list) != nil {
+		return false
+	}
+	if s.List == "" {
+		return true
+	}
+	// 分組中須有課程紀錄陣列 (空列表視為符合)
+	for _, group := range list {
+		if _, ok := jsonField(group, s.Records); ok {
+			return true
+		}
+	}
+	return len(list) == 0
+}
+
+// 依版本設定取出課程列與學生資料
+func (s inccuSchema) extract(section map[string]json.RawMessage, t *rawTranscript) {
+	mergeProfileFields(t.Profile, profileFields(jsonRecordFields(jsonObjectAt(section, s.Profile))))
+
+	addRecords := func(raw json.RawMessage, year string) {
+		var records []map[string]json.RawMessage
+		json.Unmarshal(raw, &records)
+		for _, record := range records {
+			row := rowFromFields(len(t.Rows)+1, jsonRecordFields(record))
+			if strings.TrimSpace(row.Year) == "" {
+				row.Year = year
+			}
+			t.Rows = append(t.Rows, row)
+		}
+	}
+
+	if s.List == "" {
+		records, _ := jsonField(section, s.Records)
+		addRecords(records, "")
+		return
+	}
+	var groups []map[string]json.RawMessage
+	list, _ := jsonField(section, s.List)
+	json.Unmarshal(list, &groups)
+	for _, group := range groups {
+		records, _ := jsonField(group, s.Records)
+		year, _ := jsonField(group, s.ListYear)
+		addRecords(records, jsonScalar(year))
+	}
+}
+
+// 取出結構欄位；鍵名與記錄欄位相同，忽略大小寫、空白、底線與連字號的差異 (完全相同者優先)
+func jsonField(obj map[string]json.RawMessage, key string) (json.RawMessage, bool) {
+	if raw, ok := obj[key]; ok {
+		return raw, true
+	}
+	want := normalizeFieldName(key)
+	for k, raw := range obj {
+		if normalizeFieldName(k) == want {
+			return raw, true
+		}
+	}
+	return nil, false
+}
+
+// 依路徑取出巢狀物件 (不存在時回傳 nil)
+func jsonObjectAt(obj map[string]json.RawMessage, path []string) map[string]json.RawMessage {
+	for _, key := range path {
+		raw, ok := jsonField(obj, key)
+		if !ok {
+			return nil
+		}
+		obj = nil
+		if json.Unmarshal(raw, &obj) != nil {
+			return nil
+		}
+	}
+	return obj
+}
+
+// 描述 JSON 物件的結構 (僅列出鍵名與第一個陣列元素的鍵名，不含任何資料內容)
+func jsonShape(obj map[string]json.RawMessage) string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		var list []map[string]json.RawMessage
+		if json.Unmarshal(obj[k], &list) == nil && len(list) > 0 {
+			parts = append(parts, k+"["+strings.TrimSuffix(strings.TrimPrefix(jsonShape(list[0]), "{"), "}")+"]")
+			continue
+		}
+		parts = append(parts, k)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
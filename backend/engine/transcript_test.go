@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func readTestTranscript(t *testing.T, name string) ([]StudentCourse, string) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript, err := testChecker.ParseTranscript(data)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return transcript.Courses, transcript.Profile.Major
+}
+
+// 三種格式的同一份成績應解析出相同的課程列表
+func TestTranscriptFormatsEquivalent(t *testing.T) {
+	want, wantMajor := readTestTranscript(t, "transcript_sample.json")
+	for _, name := range []string{"transcript_sample.csv", "transcript_canonical.json"} {
+		got, major := readTestTranscript(t, name)
+		if major != wantMajor {
+			t.Errorf("%s: 主修 %q，預期 %q", name, major, wantMajor)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: 課程列表與全人系統格式不一致\n得到 %+v\n預期 %+v", name, got, want)
+		}
+	}
+}
+
+func TestTranscriptUnknownFormat(t *testing.T) {
+	for _, data := range []string{`{"foo": 1}`, `[1, 2, 3]`, "a,b\n1,2\n", ""} {
+		if _, err := testChecker.ParseTranscript([]byte(data)); err == nil {
+			t.Errorf("%q 應無法辨識", data)
+		}
+	}
+}
+
+func TestMergeTranscripts(t *testing.T) {
+	var files []TranscriptFile
+	for _, name := range []string{"transcript_sample.json", "transcript_second.csv"} {
+		data, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, TranscriptFile{Name: name, Data: data})
+	}
+	first, _ := readTestTranscript(t, "transcript_sample.json")
+
+	merged, err := testChecker.MergeTranscripts(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Major != "財務管理學系" {
+		t.Errorf("主修 %q", merged.Major)
+	}
+	// 重複的經濟學去除、衝突的財務管理保留第一筆，只新增一門課
+	if len(merged.Courses) != len(first)+1 {
+		t.Fatalf("合併後 %d 門課，預期 %d", len(merged.Courses), len(first)+1)
+	}
+
+	scores := make(map[string]string)
+	for _, c := range merged.Courses {
+		scores[c.Name+" "+c.Semester] = c.Score
+	}
+	if got := scores["財務管理 112-1"]; got != "91" {
+		t.Errorf("衝突時應保留第一個檔案的成績，得到 %q", got)
+	}
+	if got := scores["中級會計學（二） 113-1"]; got != "78" {
+		t.Errorf("修習中的紀錄應由後來的成績取代，得到 %q", got)
+	}
+
+	want := []CourseConflict{{
+		Name:     "財務管理",
+		Semester: "112-1",
+		Scores:   []string{"91", "95"},
+		Files:    []string{"transcript_sample.json", "transcript_second.csv"},
+	}}
+	if !reflect.DeepEqual(merged.Conflicts, want) {
+		t.Errorf("衝突 %+v，預期 %+v", merged.Conflicts, want)
+	}
+}
+
+func TestTranscriptDiagnostics(t *testing.T) {
+	data, err := os.ReadFile("testdata/transcript_messy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := defaultTranscriptRegistry.parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	courses, major, diagnostics := parsed.Courses, parsed.ProfileFields["major"], parsed.Diagnostics
+	if major != "財務管理學系" {
+		t.Errorf("主修 %q，預期第一個學籍的主修", major)
+	}
+	// 空白名稱與完全重複的列略過 (重複的學分不可採計兩次)，其餘 (含第二個學籍) 皆保留
+	if len(courses) != 5 {
+		t.Fatalf("解析出 %d 門課，預期 5", len(courses))
+	}
+	if c := courses[1]; c.Name != "管理學" || c.Credit != 3 || c.Score != "88" || c.Semester != "111-1" {
+		t.Errorf("欄位名稱差異未正確處理: %+v", c)
+	}
+
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, fmt.Sprintf("%d:%s", d.Row, d.Code))
+	}
+	want := []string{"3:invalid_credit", "4:empty_name", "5:unknown_score", "6:duplicate_row"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("診斷 %v，預期 %v", got, want)
+	}
+}
+
+func TestINCCUSchemaVersions(t *testing.T) {
+	data, err := os.ReadFile("testdata/transcript_sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := defaultTranscriptRegistry.parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Format != "inccu/v1" {
+		t.Errorf("格式 %q，預期 inccu/v1", parsed.Format)
+	}
+
+	// 學校更改欄位名稱時應回報結構 (僅鍵名)，而非僅回傳一般錯誤
+	renamed := []byte(`[{"課業學習": {"aboutMe": {"registerMajor": "財務管理學系"}, "gradeRecords": [{"AcademicYear": "111", "Records": [{"courseName": "經濟學"}]}]}}]`)
+	_, err = defaultTranscriptRegistry.parse(renamed)
+	var schemaErr *UnknownSchemaError
+	if !errors.As(err, &schemaErr) || !errors.Is(err, ErrUnknownSchema) || schemaErr.Shape != "{aboutMe,gradeRecords[AcademicYear,Records[courseName]]}" {
+		t.Errorf("未知結構的錯誤應包含欄位結構，得到 %v", err)
+	}
+	if strings.Contains(fmt.Sprint(err), "經濟學") {
+		t.Errorf("錯誤訊息不應包含資料內容: %v", err)
+	}
+
+	// 結構欄位僅大小寫、底線等不同時與記錄欄位相同，仍視為同一版本
+	recased := []byte(`[{"課業學習": {"AboutMe": {"registerMajor": "財務管理學系"}, "grade_record_list": [{"academicYear": "111", "gradeRecords": [{"courseName": "經濟學", "credit": "3", "score": "85"}]}]}}]`)
+	if parsed, err := defaultTranscriptRegistry.parse(recased); err != nil || parsed.Format != "inccu/v1" || len(parsed.Courses) != 1 || !strings.HasPrefix(parsed.Courses[0].Semester, "111") || parsed.ProfileFields["major"] != "財務管理學系" {
+		t.Errorf("鍵名大小寫不同的結構解析結果 %+v: %v", parsed, err)
+	}
+
+	// 新增版本後兩種結構可同時解析 (以測試自行建立的登錄表，不影響預設登錄表)
+	registry := newTranscriptRegistry(append(slices.Clone(inccuSchemas), inccuSchema{
+		Version:  "test",
+		Section:  "課業學習",
+		List:     "gradeRecords",
+		ListYear: "AcademicYear",
+		Records:  "Records",
+		Profile:  []string{"aboutMe"},
+	}))
+	parsed, err = registry.parse(renamed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Format != "inccu/test" || len(parsed.Courses) != 1 {
+		t.Errorf("新版本解析結果 %+v", parsed)
+	}
+	if parsed, err := registry.parse(data); err != nil || parsed.Format != "inccu/v1" {
+		t.Errorf("舊版本解析結果 %q: %v", parsed.Format, err)
+	}
+	if _, err := defaultTranscriptRegistry.parse(renamed); !errors.Is(err, ErrUnknownSchema) {
+		t.Errorf("預設登錄表不應受影響: %v", err)
+	}
+}
+
+func TestStudentProfile(t *testing.T) {
+	data, err := os.ReadFile("testdata/transcript_sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript, err := testChecker.ParseTranscript(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := transcript.Profile
+	want := StudentProfile{Major: "財務管理學系", DepartmentCode: "307", CollegeCode: businessCollegeCode, College: "商學院", DegreeLevel: DegreeBachelor, EnrollmentYear: 111, YearOfStudy: 3}
+	if profile != want {
+		t.Errorf("全人系統匯出檔：%+v，預期 %+v", profile, want)
+	}
+
+	// 明確提供的欄位優先於推斷結果；系所名稱含學制時以前綴比對學院
+	csv := "課程名稱,學分,成績,學年,學期,主修,雙主修,輔系,入學年度\n" +
+		"經濟學,3,85,112,1,金融學系碩士班,,,\n" +
+		"財務管理,3,90,113,1,,資訊科學系,法律學系,110\n"
+	transcript, err = testChecker.ParseTranscript([]byte(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile = transcript.Profile
+	want = StudentProfile{
+		Major:          "金融學系碩士班",
+		DepartmentCode: "302",
+		CollegeCode:    businessCollegeCode,
+		College:        "商學院",
+		DegreeLevel:    DegreeMaster,
+		EnrollmentYear: 110,
+		YearOfStudy:    4,
+		DoubleMajor:    "資訊科學系",
+		Minor:          "法律學系",
+	}
+	if profile != want {
+		t.Errorf("CSV：%+v，預期 %+v", profile, want)
+	}
+}
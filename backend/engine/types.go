@@ -0,0 +1,82 @@
+package engine
+
+// --- 結構定義 (與前端的 JSON 格式對應) ---
+
+// 單一課程紀錄 (從學生上傳的 JSON 中解析出來的扁平化結構)
+type StudentCourse struct {
+	Name         string  `json:"name"`
+	Credit       float64 `json:"credit"`
+	Score        string  `json:"score"` // 可能是數字或 "成績未到或無成績"
+	IsInProgress bool    `json:"isInProgress"`
+	IsPassed     bool    `json:"isPassed"`
+	Semester     string  `json:"semester"`
+	IsCapped     bool    `json:"isCapped"`
+}
+
+// 學程要求中的一個分類
+type ProgramRequirement struct {
+	Category   string   `json:"category"`
+	MinCount   int      `json:"min_count"`
+	MaxCount   int      `json:"max_count"`
+	MaxCredits float64  `json:"max_credits"` // 該分類最高認列學分
+	MinCredits float64  `json:"min_credits"`
+	Courses    []string `json:"courses"` // 課程名稱列表
+}
+
+// 單一學程定義
+type Program struct {
+	Name                    string               `json:"name"`
+	MinCredits              float64              `json:"min_credits"`
+	Description             string               `json:"description"`
+	URL                     string               `json:"url"`
+	Requirements            []ProgramRequirement `json:"requirements"`
+	Type                    string               `json:"type"`                      // "micro" (微學程) or "credit" (學分學程)
+	GeneralEducationCourses []string             `json:"general_education_courses"` // 通識課程列表 (全域限修一門)
+}
+
+// 檢核結果中的一個分類結果
+type CategoryResult struct {
+	Category        string          `json:"category"`
+	RequiredCount   int             `json:"requiredCount"`
+	RequiredCredits float64         `json:"requiredCredits"`
+	PassedCount     int             `json:"passedCount"`
+	PassedCredits   float64         `json:"passedCredits"`
+	IsMet           bool            `json:"isMet"`
+	PassedCourses   []StudentCourse `json:"passedCourses"`
+	LimitExceeded   bool            `json:"limitExceeded"`
+	ExceededMessage string          `json:"exceededMessage"`
+}
+
+// 最終檢核結果
+type CheckResult struct {
+	ProgramName        string           `json:"programName"`
+	ProgramURL         string           `json:"programUrl"`
+	IsCompleted        bool             `json:"isCompleted"`
+	TotalPassedCredits string           `json:"totalPassedCredits"` // 傳回字串方便前端顯示
+	MinRequiredCredits string           `json:"minRequiredCredits"`
+	TotalCreditsMet    bool             `json:"totalCreditsMet"`
+	AllCategoriesMet   bool             `json:"allCategoriesMet"`
+	CategoryResults    []CategoryResult `json:"categoryResults"`
+	InProgressCourses  []StudentCourse  `json:"inProgressCourses"`
+	ProgramDescription string           `json:"programDescription"`
+	AvgScoreRequired   bool             `json:"avgScoreRequired"`   // 是否需要檢核平均成績
+	AvgScore           string           `json:"avgScore"`           // 平均成績
+	AvgScoreMet        bool             `json:"avgScoreMet"`        // 平均成績是否達標
+	AvgScoreThreshold  string           `json:"avgScoreThreshold"`  // 平均成績門檻
+	RestrictionMessage string           `json:"restrictionMessage"` // 資格限制訊息
+}
+
+// 推薦結果結構
+type Recommendation struct {
+	ProgramID           string           `json:"programID"`
+	ProgramName         string           `json:"programName"`
+	ProgramURL          string           `json:"programUrl"`
+	Type                string           `json:"type"`
+	TotalPassedCredits  float64          `json:"totalPassedCredits"`
+	MinCredits          float64          `json:"minCredits"`
+	PassedPrereqCredits float64          `json:"passedPrereqCredits"` // 新增：已修先修學分
+	CompletionRate      float64          `json:"completionRate"`
+	IsCompleted         bool             `json:"isCompleted"`
+	IsRestricted        bool             `json:"isRestricted"`
+	CategoryResults     []CategoryResult `json:"categoryResults"`
+}
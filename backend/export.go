@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"internal.company/NCCU-Pro/engine"
+)
+
+// --- 檢核與推薦結果的 CSV / XLSX 匯出 ---
+
+// 每列為一個分類 (rowType=category)、一門認列課程 (course) 或一門修習中課程 (inProgress)；
+// 欄位名稱與 CategoryResult、StudentCourse 的 JSON 欄位一致
+var resultRowHeader = []string{
+	"rowType",
+	"category", "requiredCount", "requiredCredits", "passedCount", "passedCredits", "isMet", "limitExceeded", "exceededMessage",
+	"name", "credit", "score", "isInProgress", "isPassed", "semester", "isCapped",
+}
+
+var recommendationHeader = []string{
+	"programID", "programName", "type", "totalPassedCredits", "minCredits", "passedPrereqCredits", "completionRate", "isCompleted", "isRestricted",
+}
+
+func courseCells(c engine.StudentCourse) []any {
+	return []any{c.Name, c.Credit, c.Score, c.IsInProgress, c.IsPassed, c.Semester, c.IsCapped}
+}
+
+// 將分類結果展開為列 (分類列之後接著該分類認列的課程)
+func resultRows(categories []engine.CategoryResult, inProgress []engine.StudentCourse) [][]any {
+	var rows [][]any
+	for _, cat := range categories {
+		rows = append(rows, []any{
+			"category",
+			cat.Category, cat.RequiredCount, cat.RequiredCredits, cat.PassedCount, cat.PassedCredits, cat.IsMet, cat.LimitExceeded, cat.ExceededMessage,
+			"", "", "", "", "", "", "",
+		})
+		for _, c := range cat.PassedCourses {
+			row := []any{"course", cat.Category, "", "", "", "", "", "", ""}
+			rows = append(rows, append(row, courseCells(c)...))
+		}
+	}
+	for _, c := range inProgress {
+		row := []any{"inProgress", "", "", "", "", "", "", "", ""}
+		rows = append(rows, append(row, courseCells(c)...))
+	}
+	return rows
+}
+
+func recommendationCells(rec engine.Recommendation) []any {
+	return []any{rec.ProgramID, rec.ProgramName, rec.Type, rec.TotalPassedCredits, rec.MinCredits, rec.PassedPrereqCredits, rec.CompletionRate, rec.IsCompleted, rec.IsRestricted}
+}
+
+// 檢核結果：每個學程一張工作表
+func checkResultSheets(results []engine.CheckResult) []xlsxSheet {
+	var sheets []xlsxSheet
+	for _, result := range results {
+		sheets = append(sheets, xlsxSheet{
+			Name:   result.ProgramName,
+			Header: resultRowHeader,
+			Rows:   resultRows(result.CategoryResults, result.InProgressCourses),
+		})
+	}
+	return sheets
+}
+
+// 推薦結果：第一張為總覽，其後每個學程一張工作表
+func recommendationSheets(recs []engine.Recommendation) []xlsxSheet {
+	summary := xlsxSheet{Name: "推薦總覽", Header: recommendationHeader}
+	for _, rec := range recs {
+		summary.Rows = append(summary.Rows, recommendationCells(rec))
+	}
+	sheets := []xlsxSheet{summary}
+	for _, rec := range recs {
+		sheets = append(sheets, xlsxSheet{
+			Name:   rec.ProgramName,
+			Header: resultRowHeader,
+			Rows:   resultRows(rec.CategoryResults, nil),
+		})
+	}
+	return sheets
+}
+
+// CSV 只有一張表，因此在每列前加上學程名稱
+func writeCheckResultsCSV(w io.Writer, results []engine.CheckResult) error {
+	cw := newReportCSVWriter(w)
+	cw.Write(append([]string{"programName"}, resultRowHeader...))
+	for _, result := range results {
+		for _, row := range resultRows(result.CategoryResults, result.InProgressCourses) {
+			cw.Write(csvRecord(append([]any{result.ProgramName}, row...)))
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// 推薦結果的 CSV：每列前加上該學程的推薦摘要
+func writeRecommendationsCSV(w io.Writer, recs []engine.Recommendation) error {
+	cw := newReportCSVWriter(w)
+	cw.Write(append(append([]string{}, recommendationHeader...), resultRowHeader...))
+	for _, rec := range recs {
+		for _, row := range resultRows(rec.CategoryResults, nil) {
+			cw.Write(csvRecord(append(recommendationCells(rec), row...)))
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// 寫入 UTF-8 BOM，讓 Excel 正確辨識中文
+func newReportCSVWriter(w io.Writer) *csv.Writer {
+	io.WriteString(w, "\ufeff")
+	return csv.NewWriter(w)
+}
+
+func csvRecord(cells []any) []string {
+	record := make([]string, len(cells))
+	for i, v := range cells {
+		switch v := v.(type) {
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return record
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// --- 請求記錄 (log/slog) ---
+//
+// 依「資料不落地」原則，記錄中不得出現上傳的課程名稱、成績或主修：
+// redactHandler 只保留 safeLogKeys 中的欄位，其餘欄位的值一律以 redactedValue 取代。
+// 新增記錄欄位時，須確認其值不可能來自上傳內容，才可加入 safeLogKeys。
+
+// 請求 ID 的標頭 (可由反向代理帶入，否則由伺服器產生)
+const requestIDHeader = "X-Request-ID"
+
+const redactedValue = "[REDACTED]"
+
+// 可原樣輸出的記錄欄位
+var safeLogKeys = map[string]bool{
+	"request_id":  true,
+	"method":      true,
+	"route":       true, // 路由樣板，例如 /api/v1/programs/{id}/check
+	"status":      true,
+	"duration_ms": true,
+	"bytes":       true,
+	"error_code":  true, // API 錯誤代碼 (errCode* 常數)
+	"reason":      true, // 錯誤的細分原因 (成績檔解析失敗原因或報表格式)
+	"format":      true, // 成績檔或報表格式
+	"count":       true,
+	"shape":       true, // 全人系統匯出檔的欄位結構 (僅鍵名)
+	"version":     true,
+	"programs":    true,
+	"port":        true,
+	// 刻意不含 error：錯誤訊息可能引用上傳內容 (例如無法解析的成績)，請改記錄 error_code 或 reason
+}
+
+// 包裝 slog.Handler，遮蔽不在 safeLogKeys 中的欄位
+type redactHandler struct {
+	next slog.Handler
+}
+
+func newRedactHandler(next slog.Handler) slog.Handler {
+	return &redactHandler{next: next}
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	}
+	if !safeLogKeys[a.Key] {
+		return slog.String(a.Key, redactedValue)
+	}
+	return a
+}
+
+// 建立伺服器使用的 logger；format 為 json 時輸出 JSON，否則為 key=value 文字格式
+func newLogger(w io.Writer, format string) *slog.Logger {
+	var handler slog.Handler
+	if strings.EqualFold(format, "json") {
+		handler = slog.NewJSONHandler(w, nil)
+	} else {
+		handler = slog.NewTextHandler(w, nil)
+	}
+	return slog.New(newRedactHandler(handler))
+}
+
+// --- 請求 ID ---
+
+// 接受反向代理帶入的請求 ID 時限制字元與長度，避免將任意內容寫入記錄
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// 記錄每個請求的路由、狀態碼、處理時間與錯誤代碼，並於回應標頭附上請求 ID
+func requestLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		rec := recorderFor(w)
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+
+		attrs := []slog.Attr{
+			slog.String("request_id", id),
+			slog.String("method", r.Method),
+			slog.String("route", routeTemplate(r)),
+			slog.Int("status", rec.statusCode()),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.Int("bytes", rec.bytes),
+		}
+		level := slog.LevelInfo
+		if rec.errCode != "" {
+			attrs = append(attrs, slog.String("error_code", rec.errCode))
+			if rec.errReason != "" {
+				attrs = append(attrs, slog.String("reason", rec.errReason))
+			}
+		}
+		switch status := rec.statusCode(); {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		slog.LogAttrs(r.Context(), level, "HTTP 請求", attrs...)
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// 將預設 logger 暫時改為輸出 JSON 至緩衝區
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(newLogger(&buf, "json"))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("記錄不是 JSON: %s", line)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestRequestLog(t *testing.T) {
+	logs := captureLogs(t)
+	router := newRouter(testChecker, defaultConfig(), nil)
+
+	files := map[string]string{"student_json": "transcript_sample.json"}
+	req := newMultipartRequest(t, "POST", "/api/v1/programs/CFA/check", files, nil)
+	req.Header.Set(requestIDHeader, "trace-123")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got := rec.Header().Get(requestIDHeader); got != "trace-123" {
+		t.Errorf("回應的請求 ID 為 %q", got)
+	}
+
+	// 無法辨識的成績檔，內容與檔名皆不得寫入記錄
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("student_json", "王小明.txt")
+	fw.Write([]byte("經濟學 61.5 財務管理學系"))
+	mw.Close()
+	req = httptest.NewRequest("POST", "/api/v1/check", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set(requestIDHeader, "含有 空白\n的 ID")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	generated := rec.Header().Get(requestIDHeader)
+	if !validRequestID.MatchString(generated) {
+		t.Errorf("無效的請求 ID 應改由伺服器產生，得到 %q", generated)
+	}
+
+	out := logs.String()
+	for _, leak := range []string{"經濟學", "財務管理學系", "王小明", "61.5"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("記錄含有上傳內容 %q:\n%s", leak, out)
+		}
+	}
+
+	var requests []map[string]any
+	for _, entry := range decodeLogLines(t, logs) {
+		if entry["msg"] == "HTTP 請求" {
+			requests = append(requests, entry)
+		}
+	}
+	if len(requests) != 2 {
+		t.Fatalf("預期 2 筆請求記錄，得到 %d 筆:\n%s", len(requests), out)
+	}
+	ok, failed := requests[0], requests[1]
+	if ok["request_id"] != "trace-123" || ok["route"] != "/api/v1/programs/{id}/check" || ok["status"] != float64(200) || ok["level"] != "INFO" {
+		t.Errorf("成功請求的記錄 %v", ok)
+	}
+	if _, has := ok["duration_ms"]; !has {
+		t.Errorf("缺少處理時間: %v", ok)
+	}
+	if failed["request_id"] != generated || failed["status"] != float64(422) || failed["level"] != "WARN" ||
+		failed["error_code"] != errCodeInvalidTranscript || failed["reason"] != "unknown_format" {
+		t.Errorf("失敗請求的記錄 %v", failed)
+	}
+}
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, "text").With("major", "財務管理學系")
+	logger.Info("測試",
+		"route", "/api/v1/check",
+		"course", "經濟學",
+		slog.Group("student", "score", 61.5, "count", 3),
+	)
+	out := buf.String()
+	for _, leak := range []string{"財務管理學系", "經濟學", "61.5"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("未遮蔽 %q: %s", leak, out)
+		}
+	}
+	for _, want := range []string{"route=/api/v1/check", "major=" + redactedValue, "student.score=" + redactedValue, "student.count=3"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("缺少 %q: %s", want, out)
+		}
+	}
+}
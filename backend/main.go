@@ -1,635 +1,418 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
-	"sort"
+	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
+	"internal.company/NCCU-Pro/engine"
 )
 
-// --- 結構定義 (與前端的 JSON 格式對應) ---
-
-// 單一課程紀錄 (從學生上傳的 JSON 中解析出來的扁平化結構)
-type StudentCourse struct {
-	Name         string  `json:"name"`
-	Credit       float64 `json:"credit"`
-	Score        string  `json:"score"` // 可能是數字或 "成績未到或無成績"
-	IsInProgress bool    `json:"isInProgress"`
-	IsPassed     bool    `json:"isPassed"`
-	Semester     string  `json:"semester"`
-	IsCapped     bool    `json:"isCapped"`
-}
-
-// 學程要求中的一個分類
-type ProgramRequirement struct {
-	Category   string   `json:"category"`
-	MinCount   int      `json:"min_count"`
-	MaxCount   int      `json:"max_count"`
-	MaxCredits float64  `json:"max_credits"` // 該分類最高認列學分
-	MinCredits float64  `json:"min_credits"`
-	Courses    []string `json:"courses"` // 課程名稱列表
-}
-
-// 單一學程定義
-type Program struct {
-	Name                    string               `json:"name"`
-	MinCredits              float64              `json:"min_credits"`
-	Description             string               `json:"description"`
-	URL                     string               `json:"url"`
-	Requirements            []ProgramRequirement `json:"requirements"`
-	Type                    string               `json:"type"`                      // "micro" (微學程) or "credit" (學分學程)
-	GeneralEducationCourses []string             `json:"general_education_courses"` // 通識課程列表 (全域限修一門)
-}
-
-// 檢核結果中的一個分類結果
-type CategoryResult struct {
-	Category        string          `json:"category"`
-	RequiredCount   int             `json:"requiredCount"`
-	RequiredCredits float64         `json:"requiredCredits"`
-	PassedCount     int             `json:"passedCount"`
-	PassedCredits   float64         `json:"passedCredits"`
-	IsMet           bool            `json:"isMet"`
-	PassedCourses   []StudentCourse `json:"passedCourses"`
-	LimitExceeded   bool            `json:"limitExceeded"`
-	ExceededMessage string          `json:"exceededMessage"`
-}
-
-// 最終檢核結果
-type CheckResult struct {
-	ProgramName        string           `json:"programName"`
-	ProgramURL         string           `json:"programUrl"`
-	IsCompleted        bool             `json:"isCompleted"`
-	TotalPassedCredits string           `json:"totalPassedCredits"` // 傳回字串方便前端顯示
-	MinRequiredCredits string           `json:"minRequiredCredits"`
-	TotalCreditsMet    bool             `json:"totalCreditsMet"`
-	AllCategoriesMet   bool             `json:"allCategoriesMet"`
-	CategoryResults    []CategoryResult `json:"categoryResults"`
-	InProgressCourses  []StudentCourse  `json:"inProgressCourses"`
-	ProgramDescription string           `json:"programDescription"`
-	AvgScoreRequired   bool             `json:"avgScoreRequired"`   // 是否需要檢核平均成績
-	AvgScore           string           `json:"avgScore"`           // 平均成績
-	AvgScoreMet        bool             `json:"avgScoreMet"`        // 平均成績是否達標
-	AvgScoreThreshold  string           `json:"avgScoreThreshold"`  // 平均成績門檻
-	RestrictionMessage string           `json:"restrictionMessage"` // 資格限制訊息
-}
-
-// 輔助結構：用於匹配單一學年/學期的紀錄
-type GradeRecordList struct {
-	AcademicYear string `json:"AcademicYear"`
-	GradeRecords []struct {
-		CourseName string `json:"courseName"`
-		Credit     string `json:"credit"`
-		Score      string `json:"score"`
-		// ... 其他欄位
-		AcademicYear string `json:"academicYear"`
-		Semester     string `json:"semester"`
-	} `json:"GradeRecords"`
-}
+// 學程與系所資料的預設目錄 (可由命令列參數 --data 或設定檔指定)
+const defaultDataDir = "data"
 
-// 輔助結構：用於匹配 '課業學習' 欄位
-type AcademicInfo struct {
-	GradeRecordList []GradeRecordList `json:"gradeRecordList"`
-	AboutMe         struct {
-		RegisterMajor string `json:"registerMajor"`
-	} `json:"aboutMe"`
-}
-
-// 頂層結構：用於匹配 JSON 檔案外層的陣列
-type StudentDataWrapper []struct {
-	AcademicInfo AcademicInfo `json:"課業學習"`
-}
+// --- HTTP 處理函式 ---
 
-// 推薦結果結構
-type Recommendation struct {
-	ProgramID           string           `json:"programID"`
-	ProgramName         string           `json:"programName"`
-	ProgramURL          string           `json:"programUrl"`
-	Type                string           `json:"type"`
-	TotalPassedCredits  float64          `json:"totalPassedCredits"`
-	MinCredits          float64          `json:"minCredits"`
-	PassedPrereqCredits float64          `json:"passedPrereqCredits"` // 新增：已修先修學分
-	CompletionRate      float64          `json:"completionRate"`
-	IsCompleted         bool             `json:"isCompleted"`
-	IsRestricted        bool             `json:"isRestricted"`
-	CategoryResults     []CategoryResult `json:"categoryResults"`
+// 用於防止休眠的健康檢查
+func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
 }
 
-// --- 全局變數 ---
-var programs map[string]Program
-var programsByCollege map[string]map[string]Program
-var businessMajors map[string]bool
-
-// --- 輔助函式 ---
-
-// 檢查分數是否及格 (Go 實作)
-func isPassed(scoreStr string) bool {
-	const PASSING_SCORE = 60.0
-	score, err := strconv.ParseFloat(scoreStr, 64)
-	return err == nil && score >= PASSING_SCORE
+// 獲取依學院分類的學程列表 (舊版 /api/programs)
+func getPrograms(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, requestChecker(r).Catalog().ByCollege())
 }
 
-// 檢查是否修習中
-func isInProgress(scoreStr string) bool {
-	return strings.TrimSpace(scoreStr) == "成績未到或無成績"
+// 輔助函式：從請求中解析學生資料；解析警告與成績衝突的數量以 X-Transcript-Warnings 標頭回傳，
+// 詳細內容可透過 /transcript 端點取得
+func parseStudentDataFromRequest(w http.ResponseWriter, r *http.Request) ([]engine.StudentCourse, engine.StudentProfile, *APIError) {
+	transcript, apiErr := parseTranscriptFromRequest(r)
+	if apiErr != nil {
+		return nil, engine.StudentProfile{}, apiErr
+	}
+	if n := len(transcript.Diagnostics) + len(transcript.Conflicts); n > 0 {
+		w.Header().Set(transcriptWarningsHeader, strconv.Itoa(n))
+	}
+	return transcript.Courses, transcript.Profile, nil
 }
 
-// 載入學程定義
-func loadPrograms() error {
-	programsByCollege = make(map[string]map[string]Program)
-	programs = make(map[string]Program)
-
-	// 定義檔案與學程類型的對應
-	files := map[string]string{
-		"data/micro_programs.json":              "micro",
-		"data/credit_programs.json":             "credit",
-		"data/commerce_specialty_programs.json": "specialty",
+// 輔助函式：讀取請求中的所有 student_json 檔案並合併
+func parseTranscriptFromRequest(r *http.Request) (*engine.Transcript, *APIError) {
+	// 1. 解析 multipart 表單 (上限與記憶體用量相同，檔案不會暫存至磁碟)
+	limit := uploadLimit(r)
+	err := r.ParseMultipartForm(limit)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		transcriptParseFailures.inc(errCodeUploadTooLarge)
+		return nil, newAPIError(http.StatusRequestEntityTooLarge, errCodeUploadTooLarge, fmt.Sprintf("上傳的檔案超過大小上限 (%d MB)", limit>>20), "student_json")
+	}
+	if err != nil {
+		transcriptParseFailures.inc(errCodeInvalidForm)
+		return nil, newAPIError(http.StatusBadRequest, errCodeInvalidForm, fmt.Sprintf("解析表單失敗: %v", err), "")
 	}
 
-	for filename, pType := range files {
-		file, err := os.ReadFile(filename)
+	// 2. 讀取學生成績檔 (可上傳多個)
+	headers := r.MultipartForm.File["student_json"]
+	if len(headers) == 0 {
+		transcriptParseFailures.inc(errCodeMissingFile)
+		return nil, newAPIError(http.StatusBadRequest, errCodeMissingFile, fmt.Sprintf("讀取檔案失敗: %v", http.ErrMissingFile), "student_json")
+	}
+	var files []engine.TranscriptFile
+	for _, header := range headers {
+		file, err := header.Open()
 		if err != nil {
-			return err
+			return nil, newAPIError(http.StatusBadRequest, errCodeMissingFile, fmt.Sprintf("讀取檔案失敗: %v", err), "student_json")
 		}
-
-		var currentFilePrograms map[string]map[string]Program
-		err = json.Unmarshal(file, &currentFilePrograms)
+		fileBytes, err := io.ReadAll(file)
+		file.Close()
 		if err != nil {
-			return fmt.Errorf("無法解析 %s: %w", filename, err)
-		}
-
-		for college, collegePrograms := range currentFilePrograms {
-			if _, ok := programsByCollege[college]; !ok {
-				programsByCollege[college] = make(map[string]Program)
-			}
-			for id, p := range collegePrograms {
-				p.Type = pType // 標記學程類型
-				programsByCollege[college][id] = p
-				programs[id] = p
-			}
+			return nil, newAPIError(http.StatusBadRequest, errCodeMissingFile, fmt.Sprintf("讀取檔案內容失敗: %v", err), "student_json")
 		}
+		files = append(files, engine.TranscriptFile{Name: header.Filename, Data: fileBytes})
 	}
 
-	// Post-process "跨院" (Interdisciplinary) programs
-	if interdisciplinary, ok := programsByCollege["跨院"]; ok {
-		delete(programsByCollege, "跨院") // Remove the category
-
-		for id, p := range interdisciplinary {
-			originalName := p.Name
-			start := strings.LastIndex(originalName, "（")
-			end := strings.LastIndex(originalName, "）")
-
-			if start != -1 && end != -1 && end > start {
-				collegesPart := originalName[start+len("（") : end]
-				colleges := strings.Split(collegesPart, " x ")
-
-				p.Name = originalName[:start]
-				programs[id] = p // Update global map
-
-				for _, college := range colleges {
-					college = strings.TrimSpace(college)
-					if _, exists := programsByCollege[college]; !exists {
-						programsByCollege[college] = make(map[string]Program)
-					}
-					programsByCollege[college][id] = p
-				}
-			}
-		}
-	}
-	return nil
-}
-
-// 載入系所分類資料 (用於判斷商學院學生)
-func loadDepartments() error {
-	file, err := os.ReadFile("data/departments_grouped.json")
+	// 3. 解析並合併學生課程資料
+	transcript, err := requestChecker(r).MergeTranscripts(files)
+	recordTranscriptFormats(transcript, err)
 	if err != nil {
-		return err
-	}
-
-	var groups map[string]struct {
-		CategoryName string `json:"category_name"`
-		Departments  []struct {
-			Name string `json:"name"`
-		} `json:"departments"`
-	}
-	if err := json.Unmarshal(file, &groups); err != nil {
-		return fmt.Errorf("無法解析 departments_grouped.json: %w", err)
+		reason := transcriptFailureReason(err)
+		transcriptParseFailures.inc(reason)
+		apiErr := newAPIError(http.StatusUnprocessableEntity, errCodeInvalidTranscript, err.Error(), "student_json")
+		apiErr.reason = reason
+		return nil, apiErr
 	}
+	return transcript, nil
+}
 
-	businessMajors = make(map[string]bool)
-	if group, ok := groups["3"]; ok { // "3" 代表商學院
-		for _, dept := range group.Departments {
-			businessMajors[dept.Name] = true
-		}
+// 解析並合併成績檔，回傳合併後的課程列表與衝突紀錄 (不執行檢核)
+func parseTranscriptHandler(w http.ResponseWriter, r *http.Request) {
+	transcript, apiErr := parseTranscriptFromRequest(r)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
 	}
-	return nil
+	writeJSON(w, http.StatusOK, transcript)
 }
 
-// 解析並扁平化學生的歷年成績資料。
-func loadStudentData(data []byte) ([]StudentCourse, string, error) {
-	var rawData StudentDataWrapper
-
-	if err := json.Unmarshal(data, &rawData); err != nil {
-		return nil, "", fmt.Errorf("解析頂層 JSON 結構失敗: %w", err)
-	}
+// 處理檔案上傳和檢核
+func checkProgramsHandler(w http.ResponseWriter, r *http.Request) {
+	checkProgramsResponse(w, r, false)
+}
 
-	if len(rawData) == 0 || len(rawData[0].AcademicInfo.GradeRecordList) == 0 {
-		return nil, "", fmt.Errorf("JSON 結構不符預期或未找到課程紀錄")
-	}
+// 舊路徑 /api/check：錯誤以純文字與 400 回傳，不存在的學程 ID 以錯誤訊息作為學程名稱的結果回傳
+// (與 /api/v1 推出前相同)
+func legacyCheckProgramsHandler(w http.ResponseWriter, r *http.Request) {
+	checkProgramsResponse(w, r, true)
+}
 
-	flatCourses := []StudentCourse{}
-
-	// 進入 gradeRecordList
-	gradeRecordList := rawData[0].AcademicInfo.GradeRecordList
-
-	for _, academicYearRecord := range gradeRecordList {
-		if len(academicYearRecord.GradeRecords) > 0 {
-			for _, course := range academicYearRecord.GradeRecords {
-				// 確保所有字串都被清理
-				courseName := strings.TrimSpace(course.CourseName)
-				scoreStr := strings.TrimSpace(course.Score)
-				creditStr := strings.TrimSpace(course.Credit)
-				semesterStr := fmt.Sprintf("%s-%s", strings.TrimSpace(course.AcademicYear), strings.TrimSpace(course.Semester))
-
-				credit, _ := strconv.ParseFloat(creditStr, 64)
-
-				flatCourses = append(flatCourses, StudentCourse{
-					Name:         courseName,
-					Credit:       credit,
-					Score:        scoreStr,
-					IsPassed:     isPassed(scoreStr),
-					IsInProgress: isInProgress(scoreStr),
-					Semester:     semesterStr,
-				})
-			}
-		}
+func checkProgramsResponse(w http.ResponseWriter, r *http.Request, legacy bool) {
+	fail := writeError
+	if legacy {
+		fail = writeLegacyError
 	}
 
-	if len(flatCourses) == 0 {
-		return nil, "", fmt.Errorf("檔案解析成功，但未找到有效的課程紀錄")
+	// 解析學生資料
+	studentCourses, profile, apiErr := parseStudentDataFromRequest(w, r)
+	if apiErr != nil {
+		fail(w, apiErr)
+		return
 	}
 
-	major := rawData[0].AcademicInfo.AboutMe.RegisterMajor
-
-	return flatCourses, major, nil
-}
-
-// 核心檢核邏輯 (與原 JS checkProgramCompletion 邏輯對應)
-// 檢核學生課程是否符合指定學分學程的要求。
-// 注意：本函式依賴於全局變數 `programs`
-func checkProgramCompletion(programID string, courses []StudentCourse, studentMajor string) CheckResult {
-	program, ok := programs[programID]
-	if !ok {
-		// 如果學程 ID 無效，回傳一個錯誤結果
-		return CheckResult{ProgramName: fmt.Sprintf("學程 ID %s 不存在", programID)}
+	format, apiErr := responseFormat(r, checkFormats)
+	if apiErr != nil {
+		fail(w, apiErr)
+		return
 	}
 
-	// 階段 1: 預處理學程要求
-	localRequirements, programCourseNamesClean, geCourseNames, courseInstructorMap := preprocessRequirements(programID, program)
-
-	// 階段 2: 篩選並處理課程
-	completedCourses, inProgressCourses := filterAndProcessCourses(programID, courses, &localRequirements, programCourseNamesClean, geCourseNames, courseInstructorMap)
-
-	// 檢查是否有通識課程超限 (用於後續顯示)
-	geLimitExceeded := false
-	if len(program.GeneralEducationCourses) > 0 {
-		geCount := 0
-		for _, c := range completedCourses {
-			if geCourseNames[c.Name] {
-				geCount++
-			}
-		}
-		// filterAndProcessCourses 已經將多餘的通識課程移除，所以這裡我們比較原始數量
-		// 但因為我們沒有保留原始的 relevantPassed，這裡可以用一個簡單的邏輯：
-		// 如果 filterAndProcessCourses 已經處理了，我們其實不需要知道是否超限，除非要顯示警告。
-		// 為了顯示警告，我們可以在這裡重新檢查原始輸入中符合通識的數量。
-		rawGeCount := 0
-		for _, c := range courses {
-			if geCourseNames[c.Name] && c.IsPassed {
-				rawGeCount++
-			}
-		}
-		if rawGeCount > 1 {
-			geLimitExceeded = true
-		}
+	// 獲取選取的學程 ID
+	programIDsStr := r.PostFormValue("program_ids")
+	if programIDsStr == "" {
+		fail(w, newAPIError(http.StatusBadRequest, errCodeMissingProgramIDs, "請選取至少一個學程 ID", "program_ids"))
+		return
 	}
+	// 假設前端傳送的是逗號分隔的 ID 字串
+	programIDs := strings.Split(programIDsStr, ",")
 
-	totalPassedCredits := 0.0 // 將由後續計算有效學分決定
-
-	// 步驟 2 & 3: 檢核分類要求 (門數) 和總學分
-	categoryResults := []CategoryResult{}
-
-	// 特殊處理：管理會計專業學程 (management_accounting)
-	isManagementAccounting := programID == "management_accounting"
-
-	if isManagementAccounting {
-		var isMet bool
-		categoryResults, isMet, totalPassedCredits = processManagementAccounting(program, completedCourses)
-		// allCategoriesMet 將在 postprocessResults 中統一計算
-		_ = isMet
-	} else {
-		// 一般學程邏輯：呼叫 special_handlers.go 中的函式
-		categoryResults, totalPassedCredits = processStandardRequirements(localRequirements, completedCourses)
-
-		// 如果有通識課程超限，加入一個額外的分類結果顯示
-		if len(program.GeneralEducationCourses) > 0 && geLimitExceeded {
-			// 找出被採計的通識課程 (在 filterAndProcessCourses 中已處理為僅剩一門或零門)
-			var gePassed []StudentCourse
-			for _, c := range completedCourses {
-				if geCourseNames[c.Name] {
-					gePassed = append(gePassed, c)
-				}
-			}
-			geCredits := 0.0
-			for _, c := range gePassed {
-				geCredits += c.Credit
-			}
-			categoryResults = append(categoryResults, CategoryResult{
-				Category:        "通識課程 (全域限制)",
-				RequiredCount:   1, // 顯示限制
-				PassedCount:     len(gePassed),
-				PassedCredits:   geCredits,
-				IsMet:           true,
-				PassedCourses:   gePassed,
-				LimitExceeded:   true,
-				ExceededMessage: "通識課程認列以一門為限 (已自動採計最高分者)",
+	// 先確認所有學程 ID 皆存在，避免回傳部分結果
+	checker := requestChecker(r)
+	var itemErrors []APIItemError
+	var knownIDs []string
+	for i, id := range programIDs {
+		if !checker.Catalog().Has(id) {
+			itemErrors = append(itemErrors, APIItemError{
+				Index:   i,
+				Value:   id,
+				Code:    errCodeProgramNotFound,
+				Message: fmt.Sprintf("學程 ID %s 不存在", id),
 			})
+			continue
 		}
+		knownIDs = append(knownIDs, id)
 	}
-
-	// 階段 3: 後處理 (跨群檢核、平均成績、系所限制等)
-	categoryResults, allCategoriesMet, restrictionMessage, avgScoreRequired, avgScoreStr, avgScoreMet, avgScoreThreshold, totalPassedCredits := postprocessResults(programID, program, studentMajor, categoryResults, totalPassedCredits)
-
-	// 步驟 4: 總結
-	totalCreditsMet := totalPassedCredits >= program.MinCredits
-	isCompleted := totalCreditsMet && allCategoriesMet
-
-	// 若有平均成績要求且未達標，則視為未修畢
-	if avgScoreRequired && !avgScoreMet {
-		isCompleted = false
-	}
-
-	return CheckResult{
-		ProgramName:        program.Name,
-		ProgramURL:         program.URL,
-		IsCompleted:        isCompleted,
-		TotalPassedCredits: fmt.Sprintf("%.1f", totalPassedCredits),
-		MinRequiredCredits: fmt.Sprintf("%.1f", program.MinCredits),
-		TotalCreditsMet:    totalCreditsMet,
-		AllCategoriesMet:   allCategoriesMet,
-		CategoryResults:    categoryResults,
-		InProgressCourses:  inProgressCourses,
-		ProgramDescription: program.Description,
-		AvgScoreRequired:   avgScoreRequired,
-		AvgScore:           avgScoreStr,
-		AvgScoreMet:        avgScoreMet,
-		AvgScoreThreshold:  avgScoreThreshold,
-		RestrictionMessage: restrictionMessage,
-	}
-}
-
-// --- HTTP 處理函式 ---
-
-// 用於防止休眠的健康檢查
-func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
-}
-
-// 獲取學程列表
-func getPrograms(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(programsByCollege)
-}
-
-// 輔助函式：從請求中解析學生資料
-func parseStudentDataFromRequest(r *http.Request) ([]StudentCourse, string, error) {
-	// 1. 解析 multipart 表單
-	err := r.ParseMultipartForm(32 << 20) // 32MB
-	if err != nil {
-		return nil, "", fmt.Errorf("解析表單失敗: %w", err)
-	}
-
-	// 2. 讀取學生 JSON 檔案
-	file, _, err := r.FormFile("student_json")
-	if err != nil {
-		return nil, "", fmt.Errorf("讀取檔案失敗: %w", err)
+	if len(itemErrors) > 0 && !legacy {
+		apiErr := newAPIError(http.StatusUnprocessableEntity, errCodeUnknownPrograms, "部分學程 ID 不存在", "program_ids")
+		apiErr.Errors = itemErrors
+		writeError(w, apiErr)
+		return
 	}
-	defer file.Close()
 
-	fileBytes, err := io.ReadAll(file)
-	if err != nil {
-		return nil, "", fmt.Errorf("讀取檔案內容失敗: %w", err)
+	// 執行檢核
+	results := checkPrograms(r, knownIDs, studentCourses, profile)
+	recordProgramChecks("check", knownIDs, results)
+
+	// 舊路徑：不存在的學程 ID 依原順序放回，以錯誤訊息作為學程名稱
+	if len(itemErrors) > 0 {
+		all := make([]engine.CheckResult, 0, len(programIDs))
+		for i := range programIDs {
+			if len(itemErrors) > 0 && itemErrors[0].Index == i {
+				all = append(all, engine.CheckResult{ProgramName: itemErrors[0].Message})
+				itemErrors = itemErrors[1:]
+				continue
+			}
+			all = append(all, results[0])
+			results = results[1:]
+		}
+		results = all
 	}
 
-	// 3. 解析學生課程資料
-	return loadStudentData(fileBytes)
+	// 回傳結果 (JSON 或 PDF 報表)
+	writeCheckResults(w, r, format, results, profile, false)
 }
 
-// 處理檔案上傳和檢核
-func checkProgramsHandler(w http.ResponseWriter, r *http.Request) {
-	// 處理 OPTIONS 請求 (CORS 預檢)
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
+// 處理單一學程檢核
+func checkSingleProgramHandler(w http.ResponseWriter, r *http.Request) {
+	checker := requestChecker(r)
+	id := mux.Vars(r)["id"]
+	if !checker.Catalog().Has(id) {
+		writeError(w, newAPIError(http.StatusNotFound, errCodeProgramNotFound, fmt.Sprintf("學程 ID %s 不存在", id), "id"))
 		return
 	}
 
-	// 解析學生資料
-	studentCourses, major, err := parseStudentDataFromRequest(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	studentCourses, profile, apiErr := parseStudentDataFromRequest(w, r)
+	if apiErr != nil {
+		writeError(w, apiErr)
 		return
 	}
 
-	// 獲取選取的學程 ID
-	programIDsStr := r.PostFormValue("program_ids")
-	if programIDsStr == "" {
-		http.Error(w, "請選取至少一個學程 ID", http.StatusBadRequest)
+	format, apiErr := responseFormat(r, checkFormats)
+	if apiErr != nil {
+		writeError(w, apiErr)
 		return
 	}
-	// 假設前端傳送的是逗號分隔的 ID 字串
-	programIDs := strings.Split(programIDsStr, ",")
-
-	// 執行檢核
-	var results []CheckResult
-	for _, id := range programIDs {
-		result := checkProgramCompletion(id, studentCourses, major)
-		results = append(results, result)
-	}
 
-	// 回傳結果
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(results)
+	results := checkPrograms(r, []string{id}, studentCourses, profile)
+	recordProgramChecks("check", []string{id}, results)
+	writeCheckResults(w, r, format, results, profile, true)
 }
 
 // 處理學程推薦 (遍歷所有學程並回傳符合一定程度者)
 func recommendProgramsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
+	recommendProgramsResponse(w, r, writeError)
+}
+
+// 舊路徑 /api/recommend：錯誤以純文字與 400 回傳 (與 /api/v1 推出前相同)
+func legacyRecommendProgramsHandler(w http.ResponseWriter, r *http.Request) {
+	recommendProgramsResponse(w, r, writeLegacyError)
+}
+
+func recommendProgramsResponse(w http.ResponseWriter, r *http.Request, fail func(http.ResponseWriter, *APIError)) {
+	// 解析學生資料
+	studentCourses, profile, apiErr := parseStudentDataFromRequest(w, r)
+	if apiErr != nil {
+		fail(w, apiErr)
 		return
 	}
 
-	// 解析學生資料
-	studentCourses, major, err := parseStudentDataFromRequest(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	format, apiErr := responseFormat(r, recommendFormats)
+	if apiErr != nil {
+		fail(w, apiErr)
 		return
 	}
 
-	// 遍歷所有學程進行檢核
-	var recommendations []Recommendation
+	// 回傳結果 (JSON 或試算表)
+	// 各學程的結果與檢核端點共用快取 (先取得推薦、再檢核其中幾個學程時不需重新計算)
+	checker := requestChecker(r)
+	ids := checker.Catalog().IDs()
+	programsEvaluated.add(float64(len(ids)), "recommend")
+	results := checkPrograms(r, ids, studentCourses, profile)
+	writeRecommendations(w, format, checker.RecommendFromResults(ids, results, profile))
+}
 
-	for id, program := range programs {
-		isRestricted := false
-		// 若為外語專長商管學分學程且學生為商學院學生，則排除於推薦列表
-		if id == "foreign_language_student_business_primer" && businessMajors[major] {
-			isRestricted = true
-		}
-		// 若為管理會計專業學程且學生非商學院學生，則排除於推薦列表
-		if id == "management_accounting" && !businessMajors[major] {
-			isRestricted = true
-		}
+type checkerContextKey struct{}
 
-		result := checkProgramCompletion(id, studentCourses, major)
+// 將檢核器放入請求的 context，供各處理函式以 requestChecker 取得
+func withChecker(checker *engine.Checker) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), checkerContextKey{}, checker)))
+		})
+	}
+}
 
-		passed, _ := strconv.ParseFloat(result.TotalPassedCredits, 64)
-		min, _ := strconv.ParseFloat(result.MinRequiredCredits, 64)
+// 取得處理此請求的檢核器 (由 newRouter 指定)
+func requestChecker(r *http.Request) *engine.Checker {
+	return r.Context().Value(checkerContextKey{}).(*engine.Checker)
+}
 
-		// 計算先修課程學分 (用於加分與計算百分比，但不計入 TotalPassedCredits)
-		passedPrereq := 0.0
-		requiredPrereq := 0.0
+type reportFontContextKey struct{}
 
-		for _, cat := range result.CategoryResults {
-			if strings.HasPrefix(cat.Category, "先修課程") {
-				passedPrereq += cat.PassedCredits
-				requiredPrereq += cat.RequiredCredits
-			}
-		}
+// 將 PDF 報表字型放入請求的 context；font 為 nil 時停用 PDF 報表
+func withReportFont(font *trueTypeFont) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), reportFontContextKey{}, font)))
+		})
+	}
+}
 
-		// 計算完成度：(主學程已修 + 先修已修) / (主學程應修 + 先修應修)
-		totalPassed := passed + passedPrereq
-		totalRequired := min + requiredPrereq
+// 取得 PDF 報表字型 (由 newRouter 指定，未設定字型時為 nil)
+func requestReportFont(r *http.Request) *trueTypeFont {
+	font, _ := r.Context().Value(reportFontContextKey{}).(*trueTypeFont)
+	return font
+}
 
-		rate := 0.0
-		if totalRequired > 0 {
-			rate = totalPassed / totalRequired
-		}
+type uploadLimitContextKey struct{}
 
-		// 推薦門檻：完成度達 20% 以上 (避免僅修一門通識就推薦所有學程)
-		if rate >= 0.2 {
-			recommendations = append(recommendations, Recommendation{
-				ProgramID:           id,
-				ProgramName:         program.Name,
-				ProgramURL:          program.URL,
-				Type:                program.Type,
-				TotalPassedCredits:  passed,
-				MinCredits:          min,
-				PassedPrereqCredits: passedPrereq,
-				CompletionRate:      rate,
-				IsCompleted:         result.IsCompleted,
-				IsRestricted:        isRestricted,
-				CategoryResults:     result.CategoryResults,
-			})
-		}
+// 限制請求內容大小，並將上限放入 context 供解析表單時使用
+func withUploadLimit(limit int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), uploadLimitContextKey{}, limit)))
+		})
 	}
+}
 
-	// 排序：依完成度由高至低
-	sort.Slice(recommendations, func(i, j int) bool {
-		return recommendations[i].CompletionRate > recommendations[j].CompletionRate
-	})
-
-	// 6. 篩選前五名 (包含並列)
-	var topRecommendations []Recommendation
-	if len(recommendations) > 0 {
-		currentRank := 1
-		lastRate := recommendations[0].CompletionRate
-
-		for _, rec := range recommendations {
-			// 若完成度小於上一筆，則名次遞增
-			if rec.CompletionRate < lastRate {
-				currentRank++
-				lastRate = rec.CompletionRate
+// 取得此請求的上傳大小上限 (位元組，由 newRouter 依設定指定)
+func uploadLimit(r *http.Request) int64 {
+	return r.Context().Value(uploadLimitContextKey{}).(int64)
+}
+
+// CORS 中間件：allowedOrigins 含 "*" 時允許所有來源，否則僅回應清單中的來源
+func commonMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
+	allowAll := slices.Contains(allowedOrigins, "*")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if allowAll {
+				w.Header().Add("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Add("Vary", "Origin")
+				if origin := r.Header.Get("Origin"); origin != "" && slices.Contains(allowedOrigins, origin) {
+					w.Header().Add("Access-Control-Allow-Origin", origin)
+				}
 			}
+			w.Header().Add("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Add("Access-Control-Expose-Headers", transcriptWarningsHeader+", "+requestIDHeader+", Content-Disposition")
 
-			// 若名次已超過 5，則停止加入
-			if currentRank > 5 {
-				break
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusOK)
+				return
 			}
 
-			topRecommendations = append(topRecommendations, rec)
-		}
+			next.ServeHTTP(w, r)
+		})
 	}
-
-	// 回傳結果
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(topRecommendations)
 }
 
-// 輔助函式：標準化課程名稱，確保比對準確
-func normalizeCourseName(name string) string {
-	return strings.TrimSpace(name)
-}
+// 建立路由；所有處理函式皆使用 checker 的學程目錄與系所資料，PDF 報表使用 font (nil 時停用)，
+// 上傳大小與靜態檔案目錄依 cfg 設定
+func newRouter(checker *engine.Checker, cfg serverConfig, font *trueTypeFont) *mux.Router {
+	r := mux.NewRouter()
+	limiter := newRateLimiter(cfg.RateLimits, cfg.TrustedProxyHeader)
+	cache := newResultCache(cfg.ResultCacheSize, time.Duration(cfg.ResultCacheTTL))
+	r.Use(withChecker(checker), withReportFont(font), withResultCache(cache), withUploadLimit(cfg.maxUploadBytes()), requestLogMiddleware, metricsMiddleware, limiter.middleware)
+
+	r.HandleFunc("/healthcheck", healthCheckHandler).Methods("GET")
+	r.HandleFunc("/metrics", metricsHandler).Methods("GET")
+	registerAPIRoutes(r)
 
-// 簡單的 CORS 中間件範例
-func commonMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Access-Control-Allow-Origin", "*") // 生產環境建議指定前端網址
-		w.Header().Add("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	// 伺服器端產生的 HTML 報告 (不需 JavaScript)
+	r.HandleFunc("/report", reportFormHandler).Methods("GET")
+	r.HandleFunc("/report", reportPageHandler).Methods("POST")
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+	// 設定靜態檔案服務 (PWA 支援)
+	r.PathPrefix("/").Handler(http.FileServer(http.Dir(cfg.StaticDir)))
 
-		next.ServeHTTP(w, r)
-	})
+	return r
 }
 
 func main() {
-	// 1. 處理 Port：優先讀取環境變數 PORT，若無則預設為 10000 (Render 常用) 或 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// 帶有子命令時以命令列模式執行 (check、recommend、list-programs)，否則啟動伺服器
+	args := os.Args[1:]
+	if len(args) == 0 {
+		args = []string{"serve"}
 	}
+	os.Exit(runCLI(args, os.Stdout, os.Stderr))
+}
+
+// 建立設定好逾時與 CORS 的 HTTP 伺服器
+func newHTTPServer(checker *engine.Checker, cfg serverConfig, font *trueTypeFont) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.Port, // 監聽所有介面 (0.0.0.0)
+		Handler:           commonMiddleware(cfg.AllowedOrigins)(newRouter(checker, cfg, font)),
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.IdleTimeout),
+	}
+}
+
+// 啟動 HTTP 伺服器，收到 SIGINT 或 SIGTERM 時等待進行中的請求完成後關閉
+func serve(cfg serverConfig) error {
+	// 結構化記錄，上傳內容一律遮蔽
+	slog.SetDefault(newLogger(os.Stderr, cfg.LogFormat))
 
-	// 2. 讀取 micro_programs.json (假設你在 init 或 main 中讀取)
-	// 如果你依照之前的建議使用 "cd backend && ./main" 啟動
-	// 程式就能直接透過 "micro_programs.json" 讀取到檔案
-	err := loadPrograms()
+	// 讀取學程與系所資料
+	checker, err := engine.Load(cfg.DataDir)
+	recordCatalogLoad(checker, err)
 	if err != nil {
-		fmt.Printf("初始化失敗: %v\n", err)
-		os.Exit(1)
+		return err
 	}
-	// 載入系所資料
-	err = loadDepartments()
+	slog.Info("已載入學程目錄", "version", checker.Catalog().Version, "programs", len(checker.Catalog().IDs()))
+
+	// 報表字型：設定的字型檔無法使用時不啟動，未設定時僅停用 PDF 報表
+	font, err := loadReportFont(cfg)
 	if err != nil {
-		fmt.Printf("載入系所資料失敗: %v\n", err)
-		os.Exit(1)
+		return err
+	}
+	if font == nil {
+		slog.Warn("未設定報表字型 (pdfFont)，停用 PDF 報表")
 	}
 
-	r := mux.NewRouter()
-	// ... 你的路由設定 ...
+	srv := newHTTPServer(checker, cfg, font)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	r.HandleFunc("/healthcheck", healthCheckHandler).Methods("GET")
-	r.HandleFunc("/api/programs", getPrograms).Methods("GET")
-	r.HandleFunc("/api/check", checkProgramsHandler).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/recommend", recommendProgramsHandler).Methods("POST", "OPTIONS")
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+	slog.Info("伺服器已啟動", "port", cfg.Port)
 
-	// 設定靜態檔案服務 (PWA 支援)
-	// 前端檔案位於 ../frontend 目錄 (假設 backend 與 frontend 為同級目錄)
-	r.PathPrefix("/").Handler(http.FileServer(http.Dir("../frontend")))
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("伺服器啟動失敗: %w", err)
+	case <-ctx.Done():
+	}
 
-	// 3. 啟動伺服器：務必監聽 "0.0.0.0"
-	fmt.Printf("伺服器已啟動於 Port %s...\n", port)
-	err = http.ListenAndServe(":"+port, commonMiddleware(r))
-	if err != nil {
-		fmt.Printf("伺服器啟動失敗: %v\n", err)
+	slog.Info("收到結束訊號，等待進行中的請求完成")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("關閉伺服器失敗: %w", err)
 	}
+	slog.Info("伺服器已關閉")
+	return nil
 }
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"internal.company/NCCU-Pro/engine"
+)
+
+// --- 監控指標 (Prometheus 文字格式，/metrics) ---
+//
+// 只記錄彙總數據：標籤僅含路由、狀態碼、學程 ID 與錯誤原因等固定值，不含任何上傳內容。
+
+// 一組依標籤區分的計數器或量表
+type metricVec struct {
+	name, help, kind string // kind: counter 或 gauge
+	labels           []string
+
+	mu     sync.Mutex
+	values map[string]float64 // 標籤值 (以 labelSep 串接) -> 數值
+}
+
+// 直方圖 (或不含分位數的 summary：buckets 為空)
+type histogramVec struct {
+	name, help, kind string // kind: histogram 或 summary
+	labels           []string
+	buckets          []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64 // 各 bucket 的累計次數 (不含 +Inf)
+	sum    float64
+	count  uint64
+}
+
+const labelSep = "\xff"
+
+func newCounterVec(name, help string, labels ...string) *metricVec {
+	return &metricVec{name: name, help: help, kind: "counter", labels: labels, values: make(map[string]float64)}
+}
+
+func newGaugeVec(name, help string, labels ...string) *metricVec {
+	return &metricVec{name: name, help: help, kind: "gauge", labels: labels, values: make(map[string]float64)}
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	kind := "histogram"
+	if len(buckets) == 0 {
+		kind = "summary"
+	}
+	return &histogramVec{name: name, help: help, kind: kind, labels: labels, buckets: buckets, values: make(map[string]*histogramValue)}
+}
+
+func (m *metricVec) add(v float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[strings.Join(labelValues, labelSep)] += v
+}
+
+func (m *metricVec) inc(labelValues ...string) {
+	m.add(1, labelValues...)
+}
+
+func (m *metricVec) set(v float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[strings.Join(labelValues, labelSep)] = v
+}
+
+// 清除所有數值 (用於只保留最新一筆的資訊量表，例如目錄版本)
+func (m *metricVec) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.values)
+}
+
+func (h *histogramVec) observe(v float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := strings.Join(labelValues, labelSep)
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	for i, le := range h.buckets {
+		if v <= le {
+			hv.counts[i]++
+		}
+	}
+	hv.sum += v
+	hv.count++
+}
+
+func (m *metricVec) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	writeMetricHeader(w, m.name, m.help, m.kind)
+	for _, key := range sortedKeys(m.values) {
+		fmt.Fprintf(w, "%s%s %s\n", m.name, formatLabels(m.labels, key, "", ""), formatMetricValue(m.values[key]))
+	}
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeMetricHeader(w, h.name, h.help, h.kind)
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		for i, le := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "le", formatMetricValue(le)), hv.counts[i])
+		}
+		if h.kind == "histogram" {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "le", "+Inf"), hv.count)
+		}
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, key, "", ""), formatMetricValue(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, key, "", ""), hv.count)
+	}
+}
+
+func writeMetricHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 組成 {label="value",...}；extraName 非空時附加於最後 (直方圖的 le)
+func formatLabels(names []string, key, extraName, extraValue string) string {
+	var values []string
+	if len(names) > 0 {
+		values = strings.Split(key, labelSep)
+	}
+	var parts []string
+	for i, name := range names {
+		parts = append(parts, name+`="`+escapeLabelValue(values[i])+`"`)
+	}
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+extraValue+`"`)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func formatMetricValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// 請求延遲的 bucket (秒)
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var (
+	httpRequests        = newCounterVec("nccu_http_requests_total", "HTTP 請求數 (依路由、方法與狀態碼)", "route", "method", "status")
+	httpRequestDuration = newHistogramVec("nccu_http_request_duration_seconds", "HTTP 請求處理時間 (秒)", latencyBuckets, "route", "method")
+
+	rateLimitedRequests = newCounterVec("nccu_rate_limited_total", "因速率或並行限制而拒絕的請求數", "route", "reason")
+
+	transcriptParseFailures = newCounterVec("nccu_transcript_parse_failures_total", "成績檔解析失敗次數 (依原因)", "reason")
+	transcriptFormats       = newCounterVec("nccu_transcript_formats_total", "成績檔解析次數 (依偵測到的格式，含無法辨識者)", "format")
+
+	programsEvaluated     = newCounterVec("nccu_programs_evaluated_total", "檢核的學程數 (推薦會檢核目錄中所有學程)", "endpoint")
+	programChecks         = newCounterVec("nccu_program_checks_total", "各學程被指定檢核的次數", "program")
+	programCompleted      = newCounterVec("nccu_program_completed_total", "各學程檢核結果為已完成的次數", "program")
+	programCompletionRate = newHistogramVec("nccu_program_completion_rate", "各學程檢核結果的完成度 (0 到 1 以上，含先修課程)", nil, "program")
+
+	resultCacheRequests = newCounterVec("nccu_result_cache_requests_total", "檢核結果快取的查詢次數 (hit 或 miss)", "result")
+
+	catalogLoads       = newCounterVec("nccu_catalog_loads_total", "學程目錄載入次數 (依結果)", "result")
+	catalogLoadSuccess = newGaugeVec("nccu_catalog_load_success", "啟動時載入學程目錄是否成功 (1 為成功)")
+	catalogLastLoad    = newGaugeVec("nccu_catalog_last_success_timestamp_seconds", "成功載入學程目錄的時間 (Unix 秒)")
+	catalogInfo        = newGaugeVec("nccu_catalog_info", "目前使用的學程目錄版本", "version")
+	catalogPrograms    = newGaugeVec("nccu_catalog_programs", "目前學程目錄中的學程數")
+)
+
+// 記錄學程目錄的載入結果 (學程目錄僅於啟動時載入；變更定義檔後須重新啟動伺服器)
+func recordCatalogLoad(checker *engine.Checker, err error) {
+	if err != nil {
+		catalogLoads.inc("failure")
+		catalogLoadSuccess.set(0)
+		return
+	}
+	catalog := checker.Catalog()
+	catalogLoads.inc("success")
+	catalogLoadSuccess.set(1)
+	catalogLastLoad.set(float64(time.Now().Unix()))
+	catalogInfo.reset()
+	catalogInfo.set(1, catalog.Version)
+	catalogPrograms.set(float64(len(catalog.IDs())))
+}
+
+// 依偵測到的格式記錄上傳的成績檔；無法辨識者計為 unknown，全人系統的未知結構計為 inccu/unknown
+// 並記錄其欄位結構 (僅鍵名)，以便及早發現學校更改匯出格式
+func recordTranscriptFormats(transcript *engine.Transcript, err error) {
+	var schemaErr *engine.UnknownSchemaError
+	switch {
+	case errors.As(err, &schemaErr):
+		transcriptFormats.inc("inccu/unknown")
+		slog.Warn("偵測到未知的全人系統匯出結構", "shape", schemaErr.Shape)
+	case errors.Is(err, engine.ErrUnknownFormat):
+		transcriptFormats.inc("unknown")
+	case err == nil:
+		for _, format := range transcript.Formats {
+			transcriptFormats.inc(format)
+		}
+	}
+}
+
+// 成績檔解析失敗的原因 (指標標籤使用的固定值)
+func transcriptFailureReason(err error) string {
+	switch {
+	case errors.Is(err, engine.ErrUnknownFormat):
+		return "unknown_format"
+	case errors.Is(err, engine.ErrUnknownSchema):
+		return "unknown_schema"
+	case errors.Is(err, engine.ErrNoCourses):
+		return "no_courses"
+	}
+	return "malformed"
+}
+
+// 記錄指定學程的檢核結果 (results 與 ids 順序相同)
+func recordProgramChecks(endpoint string, ids []string, results []engine.CheckResult) {
+	programsEvaluated.add(float64(len(ids)), endpoint)
+	for i, id := range ids {
+		programChecks.inc(id)
+		if results[i].IsCompleted {
+			programCompleted.inc(id)
+		}
+		rate, _, _, _ := engine.CompletionRate(results[i])
+		programCompletionRate.observe(rate, id)
+	}
+}
+
+// 記錄回應狀態碼、大小與錯誤代碼 (請求記錄與監控指標共用)
+type statusRecorder struct {
+	http.ResponseWriter
+	status    int
+	bytes     int
+	errCode   string // writeError 回傳的錯誤代碼
+	errReason string // 錯誤的細分原因 (例如成績檔解析失敗的原因)
+}
+
+// 若 w 已是 statusRecorder (外層中介層建立) 則沿用，否則包裝一個新的
+func recorderFor(w http.ResponseWriter) *statusRecorder {
+	if rec, ok := w.(*statusRecorder); ok {
+		return rec
+	}
+	return &statusRecorder{ResponseWriter: w}
+}
+
+// 未呼叫 WriteHeader 或 Write 時回應狀態碼為 200
+func (s *statusRecorder) statusCode() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+// 目前請求的路由樣板 (例如 /api/v1/programs/{id})，未符合任何路由時為 unmatched
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if tpl, err := current.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
+
+// 以路由樣板 (例如 /api/v1/programs/{id}) 統計請求數與處理時間
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		rec := recorderFor(w)
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+		httpRequestDuration.observe(time.Since(start).Seconds(), route, r.Method)
+		httpRequests.inc(route, r.Method, strconv.Itoa(rec.statusCode()))
+	})
+}
+
+// GET /metrics：Prometheus 文字格式
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	httpRequests.write(w)
+	httpRequestDuration.write(w)
+	rateLimitedRequests.write(w)
+	transcriptParseFailures.write(w)
+	transcriptFormats.write(w)
+
+	programsEvaluated.write(w)
+	programChecks.write(w)
+	programCompleted.write(w)
+	programCompletionRate.write(w)
+	resultCacheRequests.write(w)
+
+	catalogLoads.write(w)
+	catalogLoadSuccess.write(w)
+	catalogLastLoad.write(w)
+	catalogInfo.write(w)
+	catalogPrograms.write(w)
+}
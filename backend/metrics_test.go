@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	router := newRouter(testChecker, defaultConfig(), nil)
+	recordCatalogLoad(testChecker, nil)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	files := map[string]string{"student_json": "transcript_sample.json"}
+	if rec := serve(newMultipartRequest(t, "POST", "/api/v1/check", files, map[string]string{"program_ids": "CFA"})); rec.Code != http.StatusOK {
+		t.Fatalf("檢核狀態碼 %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(newMultipartRequest(t, "POST", "/api/v1/recommend", files, nil)); rec.Code != http.StatusOK {
+		t.Fatalf("推薦狀態碼 %d: %s", rec.Code, rec.Body.String())
+	}
+	serve(newMultipartRequest(t, "POST", "/api/v1/check", nil, map[string]string{"program_ids": "CFA"}))
+
+	// 無法辨識的檔案內容
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("student_json", "notes.txt")
+	fw.Write([]byte("經濟學 85 分"))
+	mw.Close()
+	req := httptest.NewRequest("POST", "/api/v1/check", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	serve(req)
+
+	rec := serve(httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics 狀態碼 %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type 為 %s", ct)
+	}
+	out := rec.Body.String()
+
+	for _, want := range []string{
+		`nccu_http_requests_total{route="/api/v1/check",method="POST",status="200"}`,
+		`nccu_http_requests_total{route="/api/v1/check",method="POST",status="400"}`,
+		`nccu_http_request_duration_seconds_bucket{route="/api/v1/recommend",method="POST",le="+Inf"}`,
+		`nccu_transcript_parse_failures_total{reason="missing_file"}`,
+		`nccu_transcript_parse_failures_total{reason="unknown_format"}`,
+		`nccu_programs_evaluated_total{endpoint="check"}`,
+		`nccu_programs_evaluated_total{endpoint="recommend"}`,
+		`nccu_program_checks_total{program="CFA"}`,
+		`nccu_program_completion_rate_count{program="CFA"}`,
+		`nccu_catalog_loads_total{result="success"}`,
+		`nccu_catalog_load_success 1`,
+		`nccu_catalog_info{version="` + testChecker.Catalog().Version + `"} 1`,
+		`nccu_transcript_formats_total{format="inccu/v1"}`,
+		`nccu_transcript_formats_total{format="unknown"}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("缺少指標 %s", want)
+		}
+	}
+
+	// 指標只含彙總數據，不得出現上傳的課程、分數或主修
+	for _, leak := range []string{"經濟學", "財務管理學系", "notes.txt"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("指標含有上傳內容 %q", leak)
+		}
+	}
+}
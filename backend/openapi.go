@@ -0,0 +1,223 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenAPI 文件：由路由表 (apiRoutes) 與 Go 結構反射產生，確保與實際回傳格式一致
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// 錯誤狀態碼對應的說明
+var errorStatusDescriptions = map[int]string{
+	http.StatusBadRequest:            "表單格式錯誤或缺少必要欄位",
+	http.StatusNotFound:              "學程 ID 不存在",
+	http.StatusRequestEntityTooLarge: "上傳的檔案超過大小上限",
+	http.StatusUnprocessableEntity:   "成績檔內容無法解析或含有不存在的學程 ID",
+	http.StatusServiceUnavailable:    "伺服器未設定報表字型，無法產生 PDF 報表",
+	http.StatusTooManyRequests:       "請求過於頻繁或伺服器忙碌，請依 Retry-After 標頭的秒數後重試",
+}
+
+// 提供 OpenAPI 文件
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, buildOpenAPISpec())
+}
+
+// 依路由表產生 OpenAPI 3 文件
+func buildOpenAPISpec() map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]any{}
+
+	errorRef := schemaFor(reflect.TypeOf(APIErrorResponse{}), schemas)
+
+	for _, route := range apiRoutes {
+		op := map[string]any{
+			"operationId": route.OperationID,
+			"summary":     route.Summary,
+		}
+
+		var params []any
+		for _, m := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+			params = append(params, map[string]any{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+		for _, q := range route.Query {
+			params = append(params, map[string]any{
+				"name":        q.Name,
+				"in":          "query",
+				"required":    q.Required,
+				"description": q.Description,
+				"schema":      map[string]any{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if len(route.Form) > 0 {
+			properties := map[string]any{}
+			var required []string
+			for _, f := range route.Form {
+				prop := map[string]any{"type": "string", "description": f.Description}
+				if f.IsFile {
+					prop["format"] = "binary"
+				}
+				if f.Multiple {
+					prop = map[string]any{"type": "array", "description": f.Description, "items": map[string]any{"type": "string", "format": "binary"}}
+				}
+				properties[f.Name] = prop
+				if f.Required {
+					required = append(required, f.Name)
+				}
+			}
+			formSchema := map[string]any{"type": "object", "properties": properties}
+			if len(required) > 0 {
+				formSchema["required"] = required
+			}
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"multipart/form-data": map[string]any{"schema": formSchema},
+				},
+			}
+		}
+
+		responses := map[string]any{
+			"200": map[string]any{
+				"description": "成功",
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": schemaFor(reflect.TypeOf(route.Response), schemas),
+					},
+				},
+			},
+		}
+		content := responses["200"].(map[string]any)["content"].(map[string]any)
+		for _, format := range route.Formats {
+			if format != "json" {
+				content[reportContentTypes[format]] = map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}
+			}
+		}
+		// 所有端點皆受速率限制
+		for _, status := range append(slices.Clone(route.Errors), http.StatusTooManyRequests) {
+			responses[strconv.Itoa(status)] = map[string]any{
+				"description": errorStatusDescriptions[status],
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": errorRef},
+				},
+			}
+		}
+		op["responses"] = responses
+
+		item, ok := paths[route.Path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "NCCU Pro API",
+			"version": "1.0.0",
+		},
+		"servers":    []any{map[string]any{"url": "/api/v1"}},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+// 將 Go 型別轉為 JSON Schema；結構型別會登錄於 components 並回傳 $ref
+func schemaFor(t reflect.Type, schemas map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		// nil slice 會被編碼為 null
+		return map[string]any{"type": "array", "nullable": true, "items": schemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	case reflect.Struct:
+		// 匿名結構直接展開
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, exists := schemas[t.Name()]; !exists {
+			// 先佔位以處理遞迴結構
+			schemas[t.Name()] = nil
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+		return ref
+	}
+	return map[string]any{}
+}
+
+// 產生結構型別的物件 Schema
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, omitEmpty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+		properties[name] = schemaFor(field.Type, schemas)
+		if !omitEmpty {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// 解析結構欄位的 json tag
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
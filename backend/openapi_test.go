@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"internal.company/NCCU-Pro/engine"
+)
+
+// 取得經 JSON 編碼後的 OpenAPI 文件 (與前端實際取得的內容一致)
+func loadSpec(t *testing.T) map[string]any {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	newRouter(testChecker, defaultConfig(), nil).ServeHTTP(rec, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/openapi.json: status %d", rec.Code)
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("OpenAPI 文件不是合法的 JSON: %v", err)
+	}
+	return spec
+}
+
+// 取得指定路徑、方法與狀態碼的回應 Schema
+func responseSchema(t *testing.T, spec map[string]any, path, method string, status int) map[string]any {
+	t.Helper()
+
+	op, ok := spec["paths"].(map[string]any)[path].(map[string]any)[strings.ToLower(method)].(map[string]any)
+	if !ok {
+		t.Fatalf("OpenAPI 文件缺少 %s %s", method, path)
+	}
+	resp, ok := op["responses"].(map[string]any)[strconv.Itoa(status)].(map[string]any)
+	if !ok {
+		t.Fatalf("OpenAPI 文件中 %s %s 未宣告狀態碼 %d", method, path, status)
+	}
+	return resp["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+}
+
+// 依 Schema 驗證 JSON 值，回傳所有不符之處
+func validateSchema(value any, schema map[string]any, spec map[string]any, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		resolved, ok := spec["components"].(map[string]any)["schemas"].(map[string]any)[name].(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: 無法解析 %s", at, ref)}
+		}
+		return validateSchema(value, resolved, spec, at)
+	}
+
+	if value == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: 不可為 null", at)}
+	}
+
+	var errs []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: 預期為 object，實際為 %T", at, value)}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		extra, _ := schema["additionalProperties"].(map[string]any)
+		for key, v := range obj {
+			if propSchema, ok := props[key].(map[string]any); ok {
+				errs = append(errs, validateSchema(v, propSchema, spec, at+"."+key)...)
+			} else if extra != nil {
+				errs = append(errs, validateSchema(v, extra, spec, at+"."+key)...)
+			} else {
+				errs = append(errs, fmt.Sprintf("%s: 欄位 %q 未宣告於文件中", at, key))
+			}
+		}
+		required, _ := schema["required"].([]any)
+		for _, key := range required {
+			if _, ok := obj[key.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: 缺少必要欄位 %q", at, key))
+			}
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: 預期為 array，實際為 %T", at, value)}
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, v := range arr {
+			errs = append(errs, validateSchema(v, items, spec, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			errs = append(errs, fmt.Sprintf("%s: 預期為 string，實際為 %T", at, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs = append(errs, fmt.Sprintf("%s: 預期為 boolean，實際為 %T", at, value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			errs = append(errs, fmt.Sprintf("%s: 預期為 number，實際為 %T", at, value))
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			errs = append(errs, fmt.Sprintf("%s: 預期為 integer，實際為 %v", at, value))
+		}
+	}
+	return errs
+}
+
+// 將路徑參數代入實際值
+func fillPathParams(path string) string {
+	return pathParamPattern.ReplaceAllStringFunc(path, func(string) string { return "CFA" })
+}
+
+// 每個路由的實際回應 (成功與錯誤) 皆須符合 OpenAPI 文件
+func TestOpenAPIMatchesHandlers(t *testing.T) {
+	spec := loadSpec(t)
+	router := newRouter(testChecker, defaultConfig(), nil)
+
+	for _, route := range apiRoutes {
+		t.Run(route.OperationID, func(t *testing.T) {
+			url := "/api/v1" + fillPathParams(route.Path)
+
+			var req *http.Request
+			if len(route.Form) > 0 {
+				req = newMultipartRequest(t, route.Method, url,
+					map[string]string{"student_json": "transcript_sample.json"},
+					map[string]string{"program_ids": "CFA,fintech,marketing_undergraduate"})
+			} else {
+				req = httptest.NewRequest(route.Method, url, nil)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s %s: status %d, body %s", route.Method, url, rec.Code, rec.Body.String())
+			}
+			var body any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("回應不是合法的 JSON: %v", err)
+			}
+			for _, e := range validateSchema(body, responseSchema(t, spec, route.Path, route.Method, http.StatusOK), spec, "$") {
+				t.Error(e)
+			}
+
+			// 錯誤回應：缺少成績檔
+			if len(route.Form) == 0 {
+				return
+			}
+			rec = httptest.NewRecorder()
+			router.ServeHTTP(rec, newMultipartRequest(t, route.Method, url, nil, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("缺少成績檔時應回傳 400，實際為 %d", rec.Code)
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("錯誤回應不是合法的 JSON: %v", err)
+			}
+			for _, e := range validateSchema(body, responseSchema(t, spec, route.Path, route.Method, http.StatusBadRequest), spec, "$") {
+				t.Error(e)
+			}
+		})
+	}
+}
+
+// 路由器中所有 /api/v1 路由皆須列於 OpenAPI 文件
+func TestOpenAPICoversRoutes(t *testing.T) {
+	spec := loadSpec(t)
+	paths := spec["paths"].(map[string]any)
+
+	err := newRouter(testChecker, defaultConfig(), nil).Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(tmpl, "/api/v1/") {
+			return nil
+		}
+		path := strings.TrimPrefix(tmpl, "/api/v1")
+		methods, _ := route.GetMethods()
+		for _, m := range methods {
+			if m == "OPTIONS" {
+				continue
+			}
+			if _, ok := paths[path].(map[string]any)[strings.ToLower(m)]; !ok {
+				t.Errorf("路由 %s %s 未列於 OpenAPI 文件", m, tmpl)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 舊路徑 /api/check 與 /api/recommend 維持 /api/v1 推出前的格式：錯誤為純文字與 400，不存在的學程 ID 以錯誤訊息作為學程名稱
+func TestLegacyRoutes(t *testing.T) {
+	router := newRouter(testChecker, defaultConfig(), nil)
+
+	for _, path := range []string{"/api/check", "/api/recommend"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newMultipartRequest(t, "POST", path, nil, map[string]string{"program_ids": "CFA"}))
+		if rec.Code != http.StatusBadRequest || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+			t.Errorf("%s 缺少成績檔: status %d, Content-Type %q", path, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newMultipartRequest(t, "POST", "/api/check",
+		map[string]string{"student_json": "transcript_sample.json"},
+		map[string]string{"program_ids": "CFA,no_such_program,fintech"}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("舊路徑遇到不存在的學程 ID 應回傳 200，實際為 %d: %s", rec.Code, rec.Body.String())
+	}
+	var results []engine.CheckResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, result := range results {
+		names = append(names, result.ProgramName)
+	}
+	if len(names) != 3 || names[1] != "學程 ID no_such_program 不存在" || names[0] == "" || names[2] == "" {
+		t.Errorf("舊路徑的檢核結果 %q", names)
+	}
+
+	// /api/v1 仍以 422 回報
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newMultipartRequest(t, "POST", "/api/v1/check",
+		map[string]string{"student_json": "transcript_sample.json"},
+		map[string]string{"program_ids": "CFA,no_such_program"}))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("/api/v1/check 遇到不存在的學程 ID 應回傳 422，實際為 %d", rec.Code)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// --- 簡易 PDF 產生器 (A4 直式，支援文字、線條與表格) ---
+
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+	pdfMargin     = 48.0
+)
+
+// PDF 物件寫入器
+type pdfWriter struct {
+	objects [][]byte
+}
+
+// 新增物件並回傳物件編號
+func (w *pdfWriter) addObject(body string) int {
+	w.objects = append(w.objects, []byte(body))
+	return len(w.objects)
+}
+
+// 預留物件編號 (稍後以 setObject 填入內容)
+func (w *pdfWriter) reserve() int {
+	return w.addObject("")
+}
+
+func (w *pdfWriter) setObject(id int, body string) {
+	w.objects[id-1] = []byte(body)
+}
+
+// 新增串流物件
+func (w *pdfWriter) addStream(dict string, data []byte, compress bool) int {
+	filter := ""
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+	var obj bytes.Buffer
+	fmt.Fprintf(&obj, "<< %s /Length %d%s >>\nstream\n", dict, len(data), filter)
+	obj.Write(data)
+	obj.WriteString("\nendstream")
+	w.objects = append(w.objects, obj.Bytes())
+	return len(w.objects)
+}
+
+// 輸出完整 PDF 檔 (root 為 Catalog 物件編號)
+func (w *pdfWriter) writeTo(out io.Writer, root int) error {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(w.objects))
+	for i, obj := range w.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(obj)
+		buf.WriteString("\nendobj\n")
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.objects)+1, root, xref)
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// PDF 文件 (含版面狀態)
+type pdfDocument struct {
+	font  pdfFont
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64 // 目前書寫位置 (距頁面底部)
+}
+
+func newPDFDocument(font pdfFont) *pdfDocument {
+	d := &pdfDocument{font: font}
+	d.newPage()
+	return d
+}
+
+func (d *pdfDocument) newPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = pdfPageHeight - pdfMargin
+}
+
+// 若剩餘空間不足則換頁，回傳是否換頁
+func (d *pdfDocument) ensureSpace(height float64) bool {
+	if d.y-height < pdfMargin {
+		d.newPage()
+		return true
+	}
+	return false
+}
+
+// 在指定位置輸出文字 (y 為基線)
+func (d *pdfDocument) textAt(x, y, size float64, gray float64, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(d.page, "BT %.3g g /F1 %.1f Tf %.2f %.2f Td <%X> Tj ET\n", gray, size, x, y, d.font.encode(s))
+}
+
+func (d *pdfDocument) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page, "%.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+func (d *pdfDocument) fillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(d.page, "%.3g g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, y, w, h)
+}
+
+// 依寬度折行 (中文可於任意字元處斷行)
+func (d *pdfDocument) wrap(s string, size, maxWidth float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		var current []rune
+		for _, r := range paragraph {
+			candidate := append(current, r)
+			if len(current) > 0 && d.font.width(string(candidate), size) > maxWidth {
+				lines = append(lines, string(current))
+				current = []rune{r}
+				continue
+			}
+			current = candidate
+		}
+		lines = append(lines, string(current))
+	}
+	return lines
+}
+
+// 輸出段落文字 (自動折行與換頁)
+func (d *pdfDocument) paragraph(s string, size, gray float64) {
+	lineHeight := size * 1.5
+	for _, line := range d.wrap(s, size, pdfPageWidth-2*pdfMargin) {
+		d.ensureSpace(lineHeight)
+		d.y -= lineHeight
+		d.textAt(pdfMargin, d.y+size*0.3, size, gray, line)
+	}
+}
+
+// 垂直留白
+func (d *pdfDocument) space(h float64) {
+	d.y -= h
+}
+
+// 輸出表格 (跨頁時重新輸出表頭)；widths 為各欄佔可用寬度的比例
+func (d *pdfDocument) table(headers []string, widths []float64, rows [][]string) {
+	const size, padding = 9.0, 4.0
+	lineHeight := size * 1.4
+	total := pdfPageWidth - 2*pdfMargin
+	colWidths := make([]float64, len(widths))
+	for i, w := range widths {
+		colWidths[i] = w * total
+	}
+
+	drawRow := func(cells []string, header bool) {
+		wrapped := make([][]string, len(cells))
+		maxLines := 1
+		for i, cell := range cells {
+			wrapped[i] = d.wrap(cell, size, colWidths[i]-2*padding)
+			maxLines = max(maxLines, len(wrapped[i]))
+		}
+		height := float64(maxLines)*lineHeight + 2*padding
+		if d.ensureSpace(height) && !header {
+			d.tableHeader(headers, colWidths, size, padding, lineHeight)
+		}
+		top := d.y
+		if header {
+			d.fillRect(pdfMargin, top-height, total, height, 0.9)
+		}
+		x := pdfMargin
+		for i, lines := range wrapped {
+			for j, line := range lines {
+				d.textAt(x+padding, top-padding-float64(j+1)*lineHeight+size*0.35, size, 0, line)
+			}
+			x += colWidths[i]
+		}
+		d.y -= height
+		d.line(pdfMargin, d.y, pdfMargin+total, d.y)
+	}
+
+	d.ensureSpace(4 * lineHeight)
+	d.line(pdfMargin, d.y, pdfMargin+total, d.y)
+	drawRow(headers, true)
+	for _, row := range rows {
+		drawRow(row, false)
+	}
+}
+
+// 輸出表頭列 (換頁後使用)
+func (d *pdfDocument) tableHeader(headers []string, colWidths []float64, size, padding, lineHeight float64) {
+	total := pdfPageWidth - 2*pdfMargin
+	height := lineHeight + 2*padding
+	d.line(pdfMargin, d.y, pdfMargin+total, d.y)
+	d.fillRect(pdfMargin, d.y-height, total, height, 0.9)
+	x := pdfMargin
+	for i, h := range headers {
+		d.textAt(x+padding, d.y-padding-lineHeight+size*0.35, size, 0, h)
+		x += colWidths[i]
+	}
+	d.y -= height
+	d.line(pdfMargin, d.y, pdfMargin+total, d.y)
+}
+
+// 輸出 PDF 檔，並於每頁底部加上頁碼
+func (d *pdfDocument) writeTo(out io.Writer) error {
+	for i, page := range d.pages {
+		label := fmt.Sprintf("%d / %d", i+1, len(d.pages))
+		fmt.Fprintf(page, "BT 0.4 g /F1 8 Tf %.2f %.2f Td <%X> Tj ET\n",
+			(pdfPageWidth-d.font.width(label, 8))/2, pdfMargin/2, d.font.encode(label))
+	}
+
+	w := &pdfWriter{}
+	catalog := w.reserve()
+	pagesID := w.reserve()
+	font := d.font.writeObjects(w)
+
+	var kids []string
+	for _, page := range d.pages {
+		content := w.addStream("", page.Bytes(), true)
+		pageID := w.addObject(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesID, pdfPageWidth, pdfPageHeight, font, content))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+	}
+	w.setObject(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	w.setObject(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	return w.writeTo(out, catalog)
+}
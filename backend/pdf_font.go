@@ -0,0 +1,462 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"unicode/utf8"
+)
+
+// --- PDF 報告用字型 ---
+// 嵌入 TrueType 字型的子集 (僅保留用到的字形)，報表在任何閱讀器中的顯示結果皆相同
+
+// PDF 文字字型
+type pdfFont interface {
+	// 將字串編碼為內容串流中使用的 2 位元組字碼
+	encode(s string) []byte
+	// 字串在指定字級下的寬度 (pt)
+	width(s string, size float64) float64
+	// 寫入字型相關物件並回傳 Type0 字型物件編號
+	writeObjects(w *pdfWriter) int
+}
+
+// --- TrueType 字型 (嵌入子集) ---
+
+type trueTypeFont struct {
+	tables          map[string][]byte
+	unitsPerEm      float64
+	numGlyphs       int
+	numHMetrics     int
+	longLoca        bool
+	bbox            [4]int16
+	ascent, descent int16
+	cmap            []byte // 選用的 cmap 子表
+	cmapFormat      uint16
+	glyphCache      map[rune]uint16
+	used            map[uint16]rune // 已使用的字形 -> 對應字元 (用於 ToUnicode)
+}
+
+// 載入 TrueType 字型檔 (.ttf)
+func loadTrueTypeFont(path string) (*trueTypeFont, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 {
+		return nil, errors.New("字型檔格式錯誤")
+	}
+	if v := binary.BigEndian.Uint32(data); v != 0x00010000 && v != 0x74727565 {
+		return nil, errors.New("僅支援 TrueType 外框的字型檔 (.ttf)")
+	}
+
+	f := &trueTypeFont{
+		tables:     make(map[string][]byte),
+		glyphCache: make(map[rune]uint16),
+		used:       map[uint16]rune{0: 0},
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return nil, errors.New("字型檔表格目錄不完整")
+		}
+		tag := string(data[rec : rec+4])
+		offset := binary.BigEndian.Uint32(data[rec+8:])
+		length := binary.BigEndian.Uint32(data[rec+12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("字型檔表格 %s 超出檔案範圍", tag)
+		}
+		f.tables[tag] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "cmap"} {
+		if _, ok := f.tables[tag]; !ok {
+			return nil, fmt.Errorf("字型檔缺少 %s 表格", tag)
+		}
+	}
+
+	head := f.tables["head"]
+	hhea := f.tables["hhea"]
+	if len(head) < 54 || len(hhea) < 36 || len(f.tables["maxp"]) < 6 {
+		return nil, errors.New("字型檔表格長度不足")
+	}
+	f.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	for i := range f.bbox {
+		f.bbox[i] = int16(binary.BigEndian.Uint16(head[36+2*i:]))
+	}
+	f.longLoca = binary.BigEndian.Uint16(head[50:]) == 1
+	f.ascent = int16(binary.BigEndian.Uint16(hhea[4:]))
+	f.descent = int16(binary.BigEndian.Uint16(hhea[6:]))
+	f.numHMetrics = int(binary.BigEndian.Uint16(hhea[34:]))
+	f.numGlyphs = int(binary.BigEndian.Uint16(f.tables["maxp"][4:]))
+
+	if err := f.selectCmap(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// 選用 Unicode cmap 子表 (優先使用支援完整 Unicode 的格式 12)
+func (f *trueTypeFont) selectCmap() error {
+	cmap := f.tables["cmap"]
+	if len(cmap) < 4 {
+		return errors.New("字型檔 cmap 表格格式錯誤")
+	}
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	best := -1
+	for i := 0; i < n && 4+8*i+8 <= len(cmap); i++ {
+		rec := cmap[4+8*i:]
+		platform := binary.BigEndian.Uint16(rec)
+		encoding := binary.BigEndian.Uint16(rec[2:])
+		offset := int(binary.BigEndian.Uint32(rec[4:]))
+		if offset+2 > len(cmap) {
+			continue
+		}
+		format := binary.BigEndian.Uint16(cmap[offset:])
+		if format != 4 && format != 12 {
+			continue
+		}
+		isUnicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if !isUnicode {
+			continue
+		}
+		if best == -1 || format == 12 {
+			best = offset
+			f.cmapFormat = format
+		}
+	}
+	if best == -1 {
+		return errors.New("字型檔缺少 Unicode cmap")
+	}
+	f.cmap = cmap[best:]
+	return nil
+}
+
+// 複製字型供單一文件使用 (字型資料共用，已使用字形各自記錄)
+func (f *trueTypeFont) forDocument() *trueTypeFont {
+	c := *f
+	c.glyphCache = make(map[rune]uint16)
+	c.used = map[uint16]rune{0: 0}
+	return &c
+}
+
+// 查詢字元對應的字形編號 (找不到時回傳 0)
+func (f *trueTypeFont) glyph(r rune) uint16 {
+	if g, ok := f.glyphCache[r]; ok {
+		return g
+	}
+	g := f.lookupGlyph(r)
+	f.glyphCache[r] = g
+	return g
+}
+
+func (f *trueTypeFont) lookupGlyph(r rune) uint16 {
+	c := f.cmap
+	u16 := func(off int) int {
+		if off+2 > len(c) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(c[off:]))
+	}
+	u32 := func(off int) uint32 {
+		if off+4 > len(c) {
+			return 0
+		}
+		return binary.BigEndian.Uint32(c[off:])
+	}
+
+	if f.cmapFormat == 12 {
+		groups := int(u32(12))
+		for i := 0; i < groups; i++ {
+			g := 16 + 12*i
+			start, end := rune(u32(g)), rune(u32(g+4))
+			if r >= start && r <= end {
+				return uint16(u32(g+8) + uint32(r-start))
+			}
+		}
+		return 0
+	}
+
+	if r > 0xFFFF {
+		return 0
+	}
+	segCount := u16(6) / 2
+	endCodes := 14
+	startCodes := endCodes + 2*segCount + 2
+	idDeltas := startCodes + 2*segCount
+	idRangeOffsets := idDeltas + 2*segCount
+	for i := 0; i < segCount; i++ {
+		if int(r) > u16(endCodes+2*i) {
+			continue
+		}
+		start := u16(startCodes + 2*i)
+		if int(r) < start {
+			return 0
+		}
+		delta := u16(idDeltas + 2*i)
+		rangeOffset := u16(idRangeOffsets + 2*i)
+		if rangeOffset == 0 {
+			return uint16((int(r) + delta) & 0xFFFF)
+		}
+		g := u16(idRangeOffsets + 2*i + rangeOffset + 2*(int(r)-start))
+		if g == 0 {
+			return 0
+		}
+		return uint16((g + delta) & 0xFFFF)
+	}
+	return 0
+}
+
+// 字形寬度 (字型單位)
+func (f *trueTypeFont) advance(g uint16) float64 {
+	hmtx := f.tables["hmtx"]
+	i := int(g)
+	if i >= f.numHMetrics {
+		i = f.numHMetrics - 1
+	}
+	if i < 0 || 4*i+2 > len(hmtx) {
+		return f.unitsPerEm
+	}
+	return float64(binary.BigEndian.Uint16(hmtx[4*i:]))
+}
+
+func (f *trueTypeFont) encode(s string) []byte {
+	var b bytes.Buffer
+	for _, r := range s {
+		g := f.glyph(r)
+		if _, ok := f.used[g]; !ok {
+			f.used[g] = r
+		}
+		b.WriteByte(byte(g >> 8))
+		b.WriteByte(byte(g))
+	}
+	return b.Bytes()
+}
+
+func (f *trueTypeFont) width(s string, size float64) float64 {
+	w := 0.0
+	for _, r := range s {
+		w += f.advance(f.glyph(r))
+	}
+	return w / f.unitsPerEm * size
+}
+
+// 取得字形資料 (依 loca 表格)
+func (f *trueTypeFont) glyphData(g uint16) []byte {
+	loca, glyf := f.tables["loca"], f.tables["glyf"]
+	var start, end int
+	if f.longLoca {
+		if 4*int(g)+8 > len(loca) {
+			return nil
+		}
+		start = int(binary.BigEndian.Uint32(loca[4*int(g):]))
+		end = int(binary.BigEndian.Uint32(loca[4*int(g)+4:]))
+	} else {
+		if 2*int(g)+4 > len(loca) {
+			return nil
+		}
+		start = 2 * int(binary.BigEndian.Uint16(loca[2*int(g):]))
+		end = 2 * int(binary.BigEndian.Uint16(loca[2*int(g)+2:]))
+	}
+	if start >= end || end > len(glyf) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// 複合字形所引用的字形
+func compositeComponents(data []byte) []uint16 {
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+	const (
+		argsAreWords   = 0x0001
+		haveScale      = 0x0008
+		moreComponents = 0x0020
+		haveXYScale    = 0x0040
+		haveTwoByTwo   = 0x0080
+	)
+	var components []uint16
+	off := 10
+	for off+4 <= len(data) {
+		flags := binary.BigEndian.Uint16(data[off:])
+		components = append(components, binary.BigEndian.Uint16(data[off+2:]))
+		off += 4
+		if flags&argsAreWords != 0 {
+			off += 4
+		} else {
+			off += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			off += 2
+		case flags&haveXYScale != 0:
+			off += 4
+		case flags&haveTwoByTwo != 0:
+			off += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// 產生僅含已使用字形的字型檔 (字形編號不變，未使用的字形清空)
+func (f *trueTypeFont) subset() []byte {
+	keep := make(map[uint16]bool)
+	var queue []uint16
+	for g := range f.used {
+		queue = append(queue, g)
+	}
+	for len(queue) > 0 {
+		g := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if keep[g] || int(g) >= f.numGlyphs {
+			continue
+		}
+		keep[g] = true
+		queue = append(queue, compositeComponents(f.glyphData(g))...)
+	}
+
+	var glyf bytes.Buffer
+	loca := make([]byte, 4*(f.numGlyphs+1))
+	for g := 0; g < f.numGlyphs; g++ {
+		binary.BigEndian.PutUint32(loca[4*g:], uint32(glyf.Len()))
+		if keep[uint16(g)] {
+			glyf.Write(f.glyphData(uint16(g)))
+			for glyf.Len()%4 != 0 {
+				glyf.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*f.numGlyphs:], uint32(glyf.Len()))
+
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)  // checkSumAdjustment
+	binary.BigEndian.PutUint16(head[50:], 1) // indexToLocFormat: long
+
+	tables := map[string][]byte{
+		"head": head,
+		"hhea": f.tables["hhea"],
+		"maxp": f.tables["maxp"],
+		"hmtx": f.tables["hmtx"],
+		"loca": loca,
+		"glyf": glyf.Bytes(),
+	}
+	for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+		if t, ok := f.tables[tag]; ok {
+			tables[tag] = t
+		}
+	}
+	return buildSfnt(tables)
+}
+
+// 組合 sfnt 字型檔
+func buildSfnt(tables map[string][]byte) []byte {
+	var tags []string
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	n := len(tags)
+	entrySelector := 0
+	for (1 << (entrySelector + 1)) <= n {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+
+	var out bytes.Buffer
+	binary.Write(&out, binary.BigEndian, uint32(0x00010000))
+	binary.Write(&out, binary.BigEndian, uint16(n))
+	binary.Write(&out, binary.BigEndian, uint16(searchRange))
+	binary.Write(&out, binary.BigEndian, uint16(entrySelector))
+	binary.Write(&out, binary.BigEndian, uint16(n*16-searchRange))
+
+	offset := 12 + 16*n
+	for _, tag := range tags {
+		t := tables[tag]
+		out.WriteString(tag)
+		binary.Write(&out, binary.BigEndian, sfntChecksum(t))
+		binary.Write(&out, binary.BigEndian, uint32(offset))
+		binary.Write(&out, binary.BigEndian, uint32(len(t)))
+		offset += (len(t) + 3) &^ 3
+	}
+	for _, tag := range tags {
+		out.Write(tables[tag])
+		for out.Len()%4 != 0 {
+			out.WriteByte(0)
+		}
+	}
+	return out.Bytes()
+}
+
+func sfntChecksum(t []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(t); i += 4 {
+		var word [4]byte
+		copy(word[:], t[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+func (f *trueTypeFont) writeObjects(w *pdfWriter) int {
+	const name = "NCCUPR+ReportFont"
+	scale := func(v int16) int { return int(float64(v) * 1000 / f.unitsPerEm) }
+
+	fontData := f.subset()
+	fontFile := w.addStream(fmt.Sprintf("/Length1 %d", len(fontData)), fontData, true)
+
+	// 已使用字形的寬度
+	var glyphs []int
+	for g := range f.used {
+		glyphs = append(glyphs, int(g))
+	}
+	sort.Ints(glyphs)
+	var widths bytes.Buffer
+	for _, g := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", g, int(f.advance(uint16(g))*1000/f.unitsPerEm))
+	}
+
+	// ToUnicode 對照表 (使複製文字時能取得正確字元)
+	var cmap bytes.Buffer
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	cmap.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	cmap.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	cmap.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for i := 0; i < len(glyphs); i += 100 {
+		end := min(i+100, len(glyphs))
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", end-i)
+		for _, g := range glyphs[i:end] {
+			var buf [4]byte
+			utf16 := encodeUTF16BE(f.used[uint16(g)], buf[:0])
+			fmt.Fprintf(&cmap, "<%04X> <%X>\n", g, utf16)
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	toUnicode := w.addStream("", cmap.Bytes(), true)
+
+	descriptor := w.addObject(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		name, scale(f.bbox[0]), scale(f.bbox[1]), scale(f.bbox[2]), scale(f.bbox[3]), scale(f.ascent), scale(f.descent), scale(f.ascent), fontFile))
+	cidFont := w.addObject(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+		name, descriptor, widths.String()))
+	return w.addObject(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", name, cidFont, toUnicode))
+}
+
+// 將字元編碼為 UTF-16BE
+func encodeUTF16BE(r rune, buf []byte) []byte {
+	if r < 0 || !utf8.ValidRune(r) {
+		r = utf8.RuneError
+	}
+	if r >= 0x10000 {
+		r -= 0x10000
+		hi, lo := 0xD800+(r>>10), 0xDC00+(r&0x3FF)
+		return append(buf, byte(hi>>8), byte(hi), byte(lo>>8), byte(lo))
+	}
+	return append(buf, byte(r>>8), byte(r))
+}
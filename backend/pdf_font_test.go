@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"internal.company/NCCU-Pro/engine"
+)
+
+// 測試字型的字形：0 .notdef、1 "A"、2 "學"、3 "程" (引用字形 2 與 1 的複合字形)、4 "龘" (報表不會用到)
+var testFontGlyphs = map[rune]uint16{'A': 1, '學': 2, '程': 3, '龘': 4}
+
+// 測試用的簡單字形：一個輪廓、一個點，xMax 用於區分不同字形
+func testSimpleGlyph(xMax int16) []byte {
+	var b bytes.Buffer
+	for _, v := range []int16{1, 0, 0, xMax, 700} { // numberOfContours 與外框
+		binary.Write(&b, binary.BigEndian, v)
+	}
+	binary.Write(&b, binary.BigEndian, uint16(0)) // endPtsOfContours
+	binary.Write(&b, binary.BigEndian, uint16(0)) // instructionLength
+	b.WriteByte(0x01)                             // flags: on curve，座標為 int16
+	binary.Write(&b, binary.BigEndian, xMax)
+	binary.Write(&b, binary.BigEndian, int16(700))
+	return b.Bytes()
+}
+
+// 合成 TrueType 字型檔 (short loca；cmapFormat 為 4 或 12)，回傳檔案路徑
+func writeTestFont(t *testing.T, cmapFormat uint16) string {
+	t.Helper()
+	be := binary.BigEndian
+
+	// 複合字形：字形 2 (字組參數) 與字形 1 (位元組參數並縮放)
+	var composite bytes.Buffer
+	for _, v := range []int16{-1, 0, 0, 1000, 800} {
+		binary.Write(&composite, be, v)
+	}
+	for _, v := range []uint16{0x0001 | 0x0002 | 0x0020, 2, 0, 0, 0x0002 | 0x0008, 1, 0x0A14, 0x2000} {
+		binary.Write(&composite, be, v)
+	}
+	glyphs := [][]byte{testSimpleGlyph(500), testSimpleGlyph(600), testSimpleGlyph(1000), composite.Bytes(), testSimpleGlyph(900)}
+
+	var glyf bytes.Buffer
+	loca := make([]byte, 2*(len(glyphs)+1))
+	for g, data := range glyphs {
+		be.PutUint16(loca[2*g:], uint16(glyf.Len()/2))
+		glyf.Write(data)
+		if glyf.Len()%2 != 0 {
+			glyf.WriteByte(0)
+		}
+	}
+	be.PutUint16(loca[2*len(glyphs):], uint16(glyf.Len()/2))
+
+	head := make([]byte, 54)
+	be.PutUint32(head, 0x00010000)
+	be.PutUint32(head[12:], 0x5F0F3CF5) // magicNumber
+	be.PutUint16(head[18:], 1000)       // unitsPerEm
+	for i, v := range []int16{-50, -120, 1050, 880} {
+		be.PutUint16(head[36+2*i:], uint16(v))
+	}
+	hhea := make([]byte, 36)
+	be.PutUint32(hhea, 0x00010000)
+	be.PutUint16(hhea[4:], 880)
+	be.PutUint16(hhea[6:], uint16(0xFFFF-120+1)) // descent -120
+	be.PutUint16(hhea[34:], 3)                   // 字形 3 以後沿用最後一筆寬度
+	maxp := make([]byte, 6)
+	be.PutUint32(maxp, 0x00005000)
+	be.PutUint16(maxp[4:], uint16(len(glyphs)))
+	var hmtx bytes.Buffer
+	for _, advance := range []uint16{500, 600, 1000} {
+		binary.Write(&hmtx, be, advance)
+		binary.Write(&hmtx, be, int16(0))
+	}
+	binary.Write(&hmtx, be, []int16{0, 0}) // 字形 3、4 的 lsb
+
+	var subtable bytes.Buffer
+	encoding := uint16(1)
+	if cmapFormat == 12 {
+		encoding = 10
+		groups := [][3]uint32{{'A', 'A', 1}, {'學', '學', 2}, {'程', '程', 3}, {'龘', '龘', 4}}
+		binary.Write(&subtable, be, []uint16{12, 0})
+		binary.Write(&subtable, be, []uint32{uint32(16 + 12*len(groups)), 0, uint32(len(groups))})
+		binary.Write(&subtable, be, groups)
+	} else {
+		// "學" 以 glyphIdArray 對應，其餘以 idDelta 對應
+		type segment struct{ start, end, delta, rangeOffset uint16 }
+		delta := func(r rune) uint16 { return testFontGlyphs[r] - uint16(r) } // 以 65536 為模
+		segments := []segment{{'A', 'A', delta('A'), 0}, {'學', '學', 0, 0}, {'程', '程', delta('程'), 0}, {'龘', '龘', delta('龘'), 0}, {0xFFFF, 0xFFFF, 1, 0}}
+		n := uint16(len(segments))
+		segments[1].rangeOffset = 2 * (n - 1)
+		binary.Write(&subtable, be, []uint16{4, 16 + 8*n + 2, 0, 2 * n, 8, 2, 2*n - 8})
+		for _, s := range segments {
+			binary.Write(&subtable, be, s.end)
+		}
+		binary.Write(&subtable, be, uint16(0))
+		for _, s := range segments {
+			binary.Write(&subtable, be, s.start)
+		}
+		for _, s := range segments {
+			binary.Write(&subtable, be, s.delta)
+		}
+		for _, s := range segments {
+			binary.Write(&subtable, be, s.rangeOffset)
+		}
+		binary.Write(&subtable, be, uint16(2)) // glyphIdArray
+	}
+	var cmap bytes.Buffer
+	binary.Write(&cmap, be, []uint16{0, 1, 3, encoding})
+	binary.Write(&cmap, be, uint32(12))
+	cmap.Write(subtable.Bytes())
+
+	data := buildSfnt(map[string][]byte{
+		"head": head, "hhea": hhea, "maxp": maxp, "hmtx": hmtx.Bytes(),
+		"loca": loca, "glyf": glyf.Bytes(), "cmap": cmap.Bytes(),
+	})
+	path := filepath.Join(t.TempDir(), "report.ttf")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 載入合成的測試字型
+func loadTestFont(t *testing.T) *trueTypeFont {
+	t.Helper()
+	font, err := loadTrueTypeFont(writeTestFont(t, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+// 解析 sfnt 字型檔的表格目錄，並驗證各表格的校驗和
+func parseTestSfnt(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	tables := make(map[string][]byte)
+	n := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < n; i++ {
+		rec := data[12+16*i:]
+		tag := string(rec[:4])
+		offset, length := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+		tables[tag] = data[offset : offset+length]
+		if sum := binary.BigEndian.Uint32(rec[4:]); sum != sfntChecksum(tables[tag]) {
+			t.Errorf("表格 %s 的校驗和 %08X，預期 %08X", tag, sum, sfntChecksum(tables[tag]))
+		}
+	}
+	return tables
+}
+
+func TestTrueTypeFont(t *testing.T) {
+	for _, format := range []uint16{4, 12} {
+		font, err := loadTrueTypeFont(writeTestFont(t, format))
+		if err != nil {
+			t.Fatalf("cmap 格式 %d: %v", format, err)
+		}
+		if font.cmapFormat != format {
+			t.Errorf("選用的 cmap 格式 %d，預期 %d", font.cmapFormat, format)
+		}
+		for r, want := range testFontGlyphs {
+			if g := font.glyph(r); g != want {
+				t.Errorf("cmap 格式 %d: %q 的字形 %d，預期 %d", format, r, g, want)
+			}
+		}
+		if g := font.glyph('Z'); g != 0 {
+			t.Errorf("cmap 格式 %d: 缺字的字形 %d，預期 0", format, g)
+		}
+		// 字形 3 超出 numHMetrics，沿用最後一筆寬度 1000
+		if w := font.width("A學程", 10); w != 26 {
+			t.Errorf("cmap 格式 %d: 寬度 %v，預期 26", format, w)
+		}
+	}
+}
+
+func TestLoadTrueTypeFontErrors(t *testing.T) {
+	valid, err := os.ReadFile(writeTestFont(t, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otf := append([]byte("OTTO"), valid[4:]...)
+	noCmap := buildSfnt(map[string][]byte{"head": make([]byte, 54)})
+
+	for name, data := range map[string][]byte{"過短": valid[:8], "CFF 外框": otf, "缺少表格": noCmap} {
+		path := filepath.Join(t.TempDir(), "font.ttf")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadTrueTypeFont(path); err == nil {
+			t.Errorf("%s: 預期載入失敗", name)
+		}
+	}
+}
+
+// 子集只保留已使用的字形與複合字形引用的字形，字形編號不變
+func TestTrueTypeFontSubset(t *testing.T) {
+	font := loadTestFont(t)
+	doc := font.forDocument()
+	if got := doc.encode("A程"); !bytes.Equal(got, []byte{0, 1, 0, 3}) {
+		t.Errorf("字碼 %X，預期 00010003", got)
+	}
+	if len(font.used) != 1 {
+		t.Errorf("各文件的已使用字形不應互相影響: %v", font.used)
+	}
+
+	tables := parseTestSfnt(t, doc.subset())
+	if _, ok := tables["cmap"]; ok {
+		t.Error("子集不需要 cmap (以字形編號作為 CID)")
+	}
+	if binary.BigEndian.Uint16(tables["head"][50:]) != 1 {
+		t.Error("子集應使用 long loca")
+	}
+	loca, glyf := tables["loca"], tables["glyf"]
+	for g := 0; g < font.numGlyphs; g++ {
+		start, end := binary.BigEndian.Uint32(loca[4*g:]), binary.BigEndian.Uint32(loca[4*g+4:])
+		got := bytes.TrimRight(glyf[start:end], "\x00")
+		want := bytes.TrimRight(font.glyphData(uint16(g)), "\x00")
+		if g == 4 {
+			want = nil // 未使用的字形清空
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("字形 %d 的資料 %X，預期 %X", g, got, want)
+		}
+	}
+}
+
+// PDF 報表嵌入字型子集，並附 ToUnicode 對照表
+func TestCheckReportPDFEmbedsFont(t *testing.T) {
+	data, err := os.ReadFile("testdata/transcript_sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript, err := testChecker.ParseTranscript(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := testChecker.Check("CFA", transcript.Courses, transcript.Profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := writeCheckReportPDF(&buf, loadTestFont(t), []engine.CheckResult{result}, transcript.Profile); err != nil {
+		t.Fatal(err)
+	}
+	pdf := buf.String()
+	for _, want := range []string{"/FontFile2", "/CIDFontType2", "/NCCUPR+ReportFont", "/ToUnicode", "/Identity-H"} {
+		if !strings.Contains(pdf, want) {
+			t.Errorf("PDF 缺少 %s", want)
+		}
+	}
+}
+
+func TestLoadReportFont(t *testing.T) {
+	fontPath := writeTestFont(t, 4)
+	dataDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dataDir, "fonts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(fontPath)
+	if err := os.WriteFile(filepath.Join(dataDir, "fonts", "report.ttf"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		dataDir  string
+		pdfFont  string
+		wantFont bool
+		wantErr  bool
+	}{
+		{"設定的字型", t.TempDir(), fontPath, true, false},
+		{"資料目錄中的字型", dataDir, "", true, false},
+		{"未設定字型", t.TempDir(), "", false, false},
+		{"設定的字型不存在", dataDir, filepath.Join(t.TempDir(), "missing.ttf"), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.DataDir, cfg.PDFFont = tt.dataDir, tt.pdfFont
+			font, err := loadReportFont(cfg)
+			if (err != nil) != tt.wantErr || (font != nil) != tt.wantFont {
+				t.Errorf("字型 %v，錯誤 %v", font != nil, err)
+			}
+		})
+	}
+}
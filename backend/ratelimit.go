@@ -0,0 +1,182 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- 速率與並行限制 ---
+//
+// 每個用戶端 IP 在每個路由各有一個 token bucket；未個別設定的路由共用 default 的額度。
+// 推薦與檢核等需要檢核大量學程的端點另有全域的並行上限，超過時直接回傳 429 而非排隊等待。
+
+// 單一路由的限制 (設定檔 rateLimits 的值)
+type rateLimit struct {
+	RequestsPerMinute float64 `json:"requestsPerMinute"` // 每個用戶端每分鐘補充的請求數；0 表示不限制
+	Burst             int     `json:"burst"`             // 短時間內可連續送出的請求數
+	MaxConcurrent     int     `json:"maxConcurrent"`     // 所有用戶端合計同時處理的請求數；0 表示不限制
+}
+
+// 未個別設定的路由使用的鍵
+const defaultRateLimitKey = "default"
+
+func defaultRateLimits() map[string]rateLimit {
+	check := rateLimit{RequestsPerMinute: 60, Burst: 20, MaxConcurrent: 8}
+	return map[string]rateLimit{
+		defaultRateLimitKey:           {RequestsPerMinute: 300, Burst: 60},
+		"/api/v1/recommend":           {RequestsPerMinute: 30, Burst: 10, MaxConcurrent: 4}, // 每次檢核所有學程
+		"/api/v1/check":               check,
+		"/api/v1/programs/{id}/check": check,
+		"/report":                     check,
+	}
+}
+
+// 舊路徑 /api/... 與 /api/v1/... 共用同一設定與額度
+func rateLimitRoute(route string) string {
+	if rest, ok := strings.CutPrefix(route, "/api/"); ok && !strings.HasPrefix(rest, "v1/") {
+		return "/api/v1/" + rest
+	}
+	return route
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type bucketKey struct {
+	route, client string
+}
+
+type rateLimiter struct {
+	limits      map[string]rateLimit
+	proxyHeader string
+	now         func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*tokenBucket
+	lastSweep time.Time
+
+	slots map[string]chan struct{} // 設有並行上限的路由
+}
+
+func newRateLimiter(limits map[string]rateLimit, proxyHeader string) *rateLimiter {
+	l := &rateLimiter{
+		limits:      make(map[string]rateLimit),
+		proxyHeader: proxyHeader,
+		now:         time.Now,
+		buckets:     make(map[bucketKey]*tokenBucket),
+		slots:       make(map[string]chan struct{}),
+	}
+	for route, limit := range limits {
+		route = rateLimitRoute(route)
+		l.limits[route] = limit
+		if limit.MaxConcurrent > 0 {
+			l.slots[route] = make(chan struct{}, limit.MaxConcurrent)
+		}
+	}
+	return l
+}
+
+// 路由樣板對應的設定鍵與限制
+func (l *rateLimiter) limitFor(route string) (string, rateLimit) {
+	key := rateLimitRoute(route)
+	if limit, ok := l.limits[key]; ok {
+		return key, limit
+	}
+	return defaultRateLimitKey, l.limits[defaultRateLimitKey]
+}
+
+// 從 client 在 route 的 bucket 取出一個 token；不足時回傳需等待的時間
+func (l *rateLimiter) allow(route string, limit rateLimit, client string) (bool, time.Duration) {
+	if limit.RequestsPerMinute <= 0 {
+		return true, 0
+	}
+	rate := limit.RequestsPerMinute / 60 // 每秒補充的 token
+	burst := float64(max(limit.Burst, 1))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	key := bucketKey{route, client}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// 每分鐘清除一次已補滿的 bucket (等同未曾請求)，避免記憶體隨用戶端數量成長
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		limit := l.limits[key.route]
+		full := float64(max(limit.Burst, 1))
+		if b.tokens+now.Sub(b.last).Seconds()*limit.RequestsPerMinute/60 >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// 用戶端 IP：設定 proxyHeader 時採用該標頭 (X-Forwarded-For 取最後一個，即受信任的代理加入的位址)
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	if l.proxyHeader != "" {
+		if v := r.Header.Get(l.proxyHeader); v != "" {
+			parts := strings.Split(v, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, limit := l.limitFor(routeTemplate(r))
+		if ok, wait := l.allow(route, limit, l.clientIP(r)); !ok {
+			rateLimitedRequests.inc(route, "rate")
+			writeTooManyRequests(w, wait, newAPIError(http.StatusTooManyRequests, errCodeRateLimited, "請求過於頻繁，請稍後再試", ""))
+			return
+		}
+		if slots := l.slots[route]; slots != nil {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			default:
+				rateLimitedRequests.inc(route, "concurrency")
+				writeTooManyRequests(w, time.Second, newAPIError(http.StatusTooManyRequests, errCodeServerBusy, "伺服器忙碌中，請稍後再試", ""))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 回傳 429 並以 Retry-After 告知需等待的秒數 (至少 1 秒)
+func writeTooManyRequests(w http.ResponseWriter, wait time.Duration, err *APIError) {
+	seconds := max(int(math.Ceil(wait.Seconds())), 1)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeError(w, err)
+}
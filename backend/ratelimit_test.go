@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(map[string]rateLimit{defaultRateLimitKey: {RequestsPerMinute: 60, Burst: 2}}, "")
+	l.now = func() time.Time { return now }
+	limit := l.limits[defaultRateLimitKey]
+
+	for i := range 2 {
+		if ok, _ := l.allow(defaultRateLimitKey, limit, "192.0.2.1"); !ok {
+			t.Fatalf("第 %d 個請求應在 burst 額度內", i+1)
+		}
+	}
+	ok, wait := l.allow(defaultRateLimitKey, limit, "192.0.2.1")
+	if ok || wait != time.Second {
+		t.Errorf("超過額度: ok=%v wait=%v，預期等待 1 秒", ok, wait)
+	}
+	if ok, _ := l.allow(defaultRateLimitKey, limit, "192.0.2.2"); !ok {
+		t.Error("不同用戶端應有各自的額度")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := l.allow(defaultRateLimitKey, limit, "192.0.2.1"); !ok {
+		t.Error("等待 1 秒後應補充一個請求")
+	}
+
+	// 補滿的 bucket 會被清除
+	now = now.Add(time.Hour)
+	l.allow(defaultRateLimitKey, limit, "192.0.2.3")
+	if len(l.buckets) != 1 {
+		t.Errorf("清除後剩 %d 個 bucket，預期 1", len(l.buckets))
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.TrustedProxyHeader = "X-Forwarded-For"
+	cfg.RateLimits["/api/v1/recommend"] = rateLimit{RequestsPerMinute: 1, Burst: 1}
+	router := newRouter(testChecker, cfg, nil)
+
+	files := map[string]string{"student_json": "transcript_sample.json"}
+	recommend := func(url, forwardedFor string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := newMultipartRequest(t, "POST", url, files, nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := recommend("/api/v1/recommend", "203.0.113.9, 198.51.100.1"); rec.Code != http.StatusOK {
+		t.Fatalf("第一個請求的狀態碼 %d", rec.Code)
+	}
+	// 舊路徑共用同一額度
+	rec := recommend("/api/recommend", "198.51.100.1")
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), errCodeRateLimited) {
+		t.Fatalf("超過額度的狀態碼 %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q，預期 60", got)
+	}
+	// 以代理標頭中最後一個位址區分用戶端
+	if rec := recommend("/api/v1/recommend", "198.51.100.1, 198.51.100.2"); rec.Code != http.StatusOK {
+		t.Errorf("其他用戶端的狀態碼 %d", rec.Code)
+	}
+	// 其他路由使用 default 額度
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/programs", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("學程列表的狀態碼 %d", rec.Code)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	l := newRateLimiter(map[string]rateLimit{
+		defaultRateLimitKey: {},
+		"/slow":             {MaxConcurrent: 1},
+	}, "")
+	entered, release := make(chan struct{}), make(chan struct{})
+	r := mux.NewRouter()
+	r.Use(l.middleware)
+	r.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	})
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/slow", nil))
+		done <- rec.Code
+	}()
+	<-entered
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/slow", nil))
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), errCodeServerBusy) || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("並行上限: 狀態碼 %d，Retry-After %q: %s", rec.Code, rec.Header().Get("Retry-After"), rec.Body.String())
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("第一個請求的狀態碼 %d", code)
+	}
+	go func() { <-entered }()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/slow", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("釋放後的狀態碼 %d", rec.Code)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"internal.company/NCCU-Pro/engine"
+)
+
+// --- 結果的輸出格式 (JSON 以外的報表與試算表) ---
+
+const (
+	errCodeUnsupportedFormat = "unsupported_format"
+	errCodeReportFailed      = "report_failed"
+	errCodePDFUnavailable    = "pdf_unavailable"
+)
+
+// 報表格式：format 參數值與對應的 Content-Type
+var reportContentTypes = map[string]string{
+	"json": "application/json",
+	"html": "text/html",
+	"pdf":  "application/pdf",
+	"csv":  "text/csv",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// 各端點支援的格式 (第一個為預設)
+var (
+	checkFormats     = []string{"json", "html", "pdf", "csv", "xlsx"}
+	recommendFormats = []string{"json", "csv", "xlsx"}
+)
+
+// format 參數說明 (用於產生 OpenAPI 文件)
+func formatField(formats []string) formField {
+	return formField{Name: "format", Description: "回傳格式：" + strings.Join(formats, "、") + " (預設 json)，亦可透過 Accept 標頭指定"}
+}
+
+// 判斷回應格式：優先採用 format 參數，其次為 Accept 標頭，預設為 JSON
+func responseFormat(r *http.Request, formats []string) (string, *APIError) {
+	if format := strings.ToLower(strings.TrimSpace(r.FormValue("format"))); format != "" {
+		if !slices.Contains(formats, format) {
+			return "", newAPIError(http.StatusBadRequest, errCodeUnsupportedFormat, "不支援的回傳格式: "+format, "format")
+		}
+		return format, checkFormatAvailable(r, format)
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		for _, format := range formats {
+			if mediaType == reportContentTypes[format] {
+				return format, checkFormatAvailable(r, format)
+			}
+		}
+	}
+	return formats[0], nil
+}
+
+// PDF 報表須有伺服器設定的字型；未設定時於檢核前即回報，而非產生缺字的報表
+func checkFormatAvailable(r *http.Request, format string) *APIError {
+	if format == "pdf" && requestReportFont(r) == nil {
+		return newAPIError(http.StatusServiceUnavailable, errCodePDFUnavailable, "伺服器未設定報表字型，暫不提供 PDF 報表", "format")
+	}
+	return nil
+}
+
+// 先將報表寫入緩衝區，產生失敗時仍可回傳錯誤訊息
+func writeReport(w http.ResponseWriter, format, filename string, render func(io.Writer) error) {
+	var buf bytes.Buffer
+	if err := render(&buf); err != nil {
+		// 錯誤訊息可能含有課程名稱 (例如字型缺字)，請求記錄中只留下報表格式
+		apiErr := newAPIError(http.StatusInternalServerError, errCodeReportFailed, "產生報表失敗", "")
+		apiErr.reason = format
+		writeError(w, apiErr)
+		return
+	}
+	contentType := reportContentTypes[format]
+	if strings.HasPrefix(contentType, "text/") {
+		contentType += "; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	// HTML 報告直接於瀏覽器顯示，其餘格式以下載方式提供
+	if format != "html" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	}
+	w.Write(buf.Bytes())
+}
+
+// 依指定格式回傳檢核結果
+func writeCheckResults(w http.ResponseWriter, r *http.Request, format string, results []engine.CheckResult, profile engine.StudentProfile, single bool) {
+	switch format {
+	case "html":
+		writeReport(w, format, "program-check", func(out io.Writer) error {
+			return writeCheckReportHTML(out, results, profile)
+		})
+	case "pdf":
+		writeReport(w, format, "program-check", func(out io.Writer) error {
+			return writeCheckReportPDF(out, requestReportFont(r), results, profile)
+		})
+	case "csv":
+		writeReport(w, format, "program-check", func(out io.Writer) error {
+			return writeCheckResultsCSV(out, results)
+		})
+	case "xlsx":
+		writeReport(w, format, "program-check", func(out io.Writer) error {
+			return writeXLSX(out, checkResultSheets(results))
+		})
+	default:
+		if single {
+			writeJSON(w, http.StatusOK, results[0])
+			return
+		}
+		writeJSON(w, http.StatusOK, results)
+	}
+}
+
+// 依指定格式回傳推薦結果
+func writeRecommendations(w http.ResponseWriter, format string, recs []engine.Recommendation) {
+	switch format {
+	case "csv":
+		writeReport(w, format, "program-recommendations", func(out io.Writer) error {
+			return writeRecommendationsCSV(out, recs)
+		})
+	case "xlsx":
+		writeReport(w, format, "program-recommendations", func(out io.Writer) error {
+			return writeXLSX(out, recommendationSheets(recs))
+		})
+	default:
+		writeJSON(w, http.StatusOK, recs)
+	}
+}
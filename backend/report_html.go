@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"internal.company/NCCU-Pro/engine"
+)
+
+// --- 伺服器端產生的 HTML 報告 (不需 JavaScript 的用戶端使用) ---
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+var templateFuncs = template.FuncMap{
+	"credits":         func(v float64) string { return fmt.Sprintf("%.1f", v) },
+	"avgScoreCourses": engine.AvgScoreCourses,
+	"passedCount": func(result engine.CheckResult) int {
+		n := 0
+		for _, cat := range result.CategoryResults {
+			n += len(cat.PassedCourses)
+		}
+		return n
+	},
+}
+
+// 每個頁面各自與共用版面組合 (頁面以 "content" 區塊填入版面)
+var (
+	reportTemplate = template.Must(template.New("").Funcs(templateFuncs).ParseFS(templateFiles, "templates/layout.html", "templates/report.html"))
+	formTemplate   = template.Must(template.New("").Funcs(templateFuncs).ParseFS(templateFiles, "templates/layout.html", "templates/form.html"))
+)
+
+// 學程類型的顯示名稱 (表單分組順序)
+var programTypeLabels = []struct{ Type, Label string }{
+	{"credit", "學分學程"},
+	{"micro", "微學程"},
+	{"specialty", "專長學程"},
+}
+
+type reportPage struct {
+	Title      string
+	Profile    []string
+	Generated  string
+	Results    []engine.CheckResult
+	Disclaimer string
+}
+
+type programGroup struct {
+	Label    string
+	Programs []engine.ProgramSummary
+}
+
+type reportFormPage struct {
+	Title      string
+	Error      string
+	Groups     []programGroup
+	Disclaimer string
+}
+
+// 產生檢核結果 HTML 報告 (單一檔案，樣式內嵌)
+func writeCheckReportHTML(out io.Writer, results []engine.CheckResult, profile engine.StudentProfile) error {
+	return reportTemplate.ExecuteTemplate(out, "layout", reportPage{
+		Title:      "學程檢核報告",
+		Profile:    profile.Summary(),
+		Generated:  time.Now().Format("2006-01-02 15:04"),
+		Results:    results,
+		Disclaimer: reportDisclaimer,
+	})
+}
+
+// 顯示上傳表單；errMsg 非空時一併顯示錯誤訊息
+func renderReportForm(w http.ResponseWriter, r *http.Request, status int, errMsg string) {
+	page := reportFormPage{Title: "學程檢核報告", Error: errMsg, Disclaimer: reportDisclaimer}
+	for _, t := range programTypeLabels {
+		if list := requestChecker(r).Catalog().Search(engine.ProgramQuery{Type: t.Type}); len(list) > 0 {
+			page.Groups = append(page.Groups, programGroup{Label: t.Label, Programs: list})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := formTemplate.ExecuteTemplate(&buf, "layout", page); err != nil {
+		http.Error(w, "產生頁面失敗", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// GET /report：上傳表單
+func reportFormHandler(w http.ResponseWriter, r *http.Request) {
+	renderReportForm(w, r, http.StatusOK, "")
+}
+
+// POST /report：以表單送出的成績檔與學程產生 HTML 報告，錯誤時重新顯示表單
+func reportPageHandler(w http.ResponseWriter, r *http.Request) {
+	studentCourses, profile, apiErr := parseStudentDataFromRequest(w, r)
+	if apiErr != nil {
+		renderReportForm(w, r, apiErr.Status, apiErr.Message)
+		return
+	}
+
+	// 核取方塊會送出多個 program_ids，也接受以逗號分隔的單一欄位
+	var programIDs []string
+	for _, value := range r.PostForm["program_ids"] {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				programIDs = append(programIDs, id)
+			}
+		}
+	}
+	if len(programIDs) == 0 {
+		renderReportForm(w, r, http.StatusBadRequest, "請選取至少一個學程")
+		return
+	}
+
+	for _, id := range programIDs {
+		if !requestChecker(r).Catalog().Has(id) {
+			renderReportForm(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("學程 ID %s 不存在", id))
+			return
+		}
+	}
+	results := checkPrograms(r, programIDs, studentCourses, profile)
+	recordProgramChecks("report", programIDs, results)
+	writeCheckResults(w, r, "html", results, profile, false)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"internal.company/NCCU-Pro/engine"
+)
+
+// --- 檢核結果 PDF 報表 ---
+
+const reportDisclaimer = "本報表由學程檢核工具依上傳之修課紀錄自動產生，僅供參考。檢核邏輯雖力求準確，但仍可能因學校政策變動或特殊修課狀況而有誤差。最終修畢資格與學分認定，悉以國立政治大學教務處及各學程設置單位之正式審核結果為準。"
+
+// 載入 PDF 報表字型：使用設定的 pdfFont，未設定時使用資料目錄中的 fonts/report.ttf。
+// 未設定且預設路徑不存在時回傳 nil (停用 PDF 報表)；字型檔無法使用時回傳錯誤
+func loadReportFont(cfg serverConfig) (*trueTypeFont, error) {
+	path := cfg.PDFFont
+	if path == "" {
+		path = filepath.Join(cfg.DataDir, "fonts", "report.ttf")
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+	}
+	font, err := loadTrueTypeFont(path)
+	if err != nil {
+		return nil, fmt.Errorf("載入報表字型 %s 失敗: %w", path, err)
+	}
+	return font, nil
+}
+
+// 以 font 產生檢核結果 PDF 報表 (每個學程一頁起)
+func writeCheckReportPDF(out io.Writer, font *trueTypeFont, results []engine.CheckResult, profile engine.StudentProfile) error {
+	// 每份文件各自記錄使用到的字形，以便並行產生報表
+	doc := newPDFDocument(font.forDocument())
+
+	for i, result := range results {
+		if i > 0 {
+			doc.newPage()
+		}
+		writeCheckResultPDF(doc, result, profile)
+	}
+
+	doc.space(12)
+	doc.paragraph("免責聲明", 10, 0)
+	doc.paragraph(reportDisclaimer, 8, 0.35)
+	return doc.writeTo(out)
+}
+
+func writeCheckResultPDF(doc *pdfDocument, result engine.CheckResult, profile engine.StudentProfile) {
+	status := "未完成"
+	if result.IsCompleted {
+		status = "已完成"
+	}
+	doc.paragraph(result.ProgramName, 18, 0)
+	doc.space(4)
+
+	summary := append(profile.Summary(),
+		"檢核狀態："+status,
+		fmt.Sprintf("總學分：%s / %s", result.TotalPassedCredits, result.MinRequiredCredits),
+	)
+	if result.AvgScoreRequired {
+		met := "未達標"
+		if result.AvgScoreMet {
+			met = "已達標"
+		}
+		summary = append(summary, fmt.Sprintf("平均成績：%s (門檻 %s，%s)", result.AvgScore, result.AvgScoreThreshold, met))
+	}
+	if result.RestrictionMessage != "" {
+		summary = append(summary, "資格限制："+result.RestrictionMessage)
+	}
+	summary = append(summary, "產生時間："+time.Now().Format("2006-01-02 15:04"))
+	for _, line := range summary {
+		doc.paragraph(line, 10, 0.2)
+	}
+
+	// 分類達成情形
+	doc.space(10)
+	doc.paragraph("分類達成情形", 12, 0)
+	doc.space(4)
+	var categoryRows [][]string
+	for _, cat := range result.CategoryResults {
+		met := "否"
+		if cat.IsMet {
+			met = "是"
+		}
+		note := ""
+		if cat.LimitExceeded {
+			note = cat.ExceededMessage
+		}
+		categoryRows = append(categoryRows, []string{
+			cat.Category,
+			fmt.Sprintf("%d / %d", cat.PassedCount, cat.RequiredCount),
+			fmt.Sprintf("%.1f / %.1f", cat.PassedCredits, cat.RequiredCredits),
+			met,
+			note,
+		})
+	}
+	doc.table([]string{"分類", "門數 (已修/應修)", "學分 (已修/應修)", "達成", "備註"},
+		[]float64{0.3, 0.15, 0.17, 0.08, 0.3}, categoryRows)
+
+	// 已通過課程
+	var passedRows [][]string
+	for _, cat := range result.CategoryResults {
+		for _, c := range cat.PassedCourses {
+			name := c.Name
+			if c.IsCapped {
+				name += " (超過認列上限)"
+			}
+			passedRows = append(passedRows, []string{name, c.Semester, fmt.Sprintf("%.1f", c.Credit), c.Score, cat.Category})
+		}
+	}
+	doc.space(10)
+	doc.paragraph("已通過課程", 12, 0)
+	doc.space(4)
+	if len(passedRows) == 0 {
+		doc.paragraph("尚無可認列的已通過課程。", 9, 0.35)
+	} else {
+		doc.table([]string{"課程", "學期", "學分", "成績", "認列分類"},
+			[]float64{0.38, 0.12, 0.1, 0.1, 0.3}, passedRows)
+	}
+
+	// 修習中課程
+	if len(result.InProgressCourses) > 0 {
+		var rows [][]string
+		for _, c := range result.InProgressCourses {
+			rows = append(rows, []string{c.Name, c.Semester, fmt.Sprintf("%.1f", c.Credit)})
+		}
+		doc.space(10)
+		doc.paragraph("修習中課程 (尚未計入學分)", 12, 0)
+		doc.space(4)
+		doc.table([]string{"課程", "學期", "學分"}, []float64{0.6, 0.2, 0.2}, rows)
+	}
+
+	if result.AvgScoreRequired {
+		writeAvgScorePDF(doc, result)
+	}
+	if result.ProgramURL != "" {
+		doc.space(6)
+		doc.paragraph("學程資訊："+strings.TrimSpace(result.ProgramURL), 8, 0.35)
+	}
+}
+
+// 平均成績明細 (依學分加權)
+func writeAvgScorePDF(doc *pdfDocument, result engine.CheckResult) {
+	var rows [][]string
+	for _, c := range engine.AvgScoreCourses(result) {
+		score, _ := strconv.ParseFloat(c.Score, 64)
+		rows = append(rows, []string{c.Name, c.Semester, fmt.Sprintf("%.1f", c.Credit), c.Score, fmt.Sprintf("%.1f", score*c.Credit)})
+	}
+
+	doc.space(10)
+	doc.paragraph("平均成績明細", 12, 0)
+	doc.paragraph(fmt.Sprintf("以下課程依學分加權平均 (先修課程不列入)，目前平均 %s 分，門檻 %s 分。", result.AvgScore, result.AvgScoreThreshold), 9, 0.35)
+	doc.space(4)
+	doc.table([]string{"課程", "學期", "學分", "成績", "成績×學分"}, []float64{0.42, 0.14, 0.12, 0.14, 0.18}, rows)
+}
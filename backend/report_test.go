@@ -0,0 +1,129 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckReportFormats(t *testing.T) {
+	router := newRouter(testChecker, defaultConfig(), loadTestFont(t))
+	files := map[string]string{"student_json": "transcript_sample.json"}
+
+	tests := []struct {
+		name        string
+		url         string
+		fields      map[string]string
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"預設 JSON", "/api/v1/check", map[string]string{"program_ids": "CFA"}, "", http.StatusOK, "application/json"},
+		{"format 參數", "/api/v1/check", map[string]string{"program_ids": "CFA,fintech", "format": "pdf"}, "", http.StatusOK, "application/pdf"},
+		{"Accept 標頭", "/api/v1/programs/real_property_financial_management/check", nil, "application/pdf", http.StatusOK, "application/pdf"},
+		{"HTML", "/api/v1/check", map[string]string{"program_ids": "CFA", "format": "html"}, "", http.StatusOK, "text/html; charset=utf-8"},
+		{"HTML 表單送出", "/report", map[string]string{"program_ids": "CFA,real_property_financial_management"}, "", http.StatusOK, "text/html; charset=utf-8"},
+		{"HTML 表單錯誤", "/report", nil, "", http.StatusBadRequest, "text/html; charset=utf-8"},
+		{"CSV", "/api/v1/check", map[string]string{"program_ids": "CFA,fintech", "format": "csv"}, "", http.StatusOK, "text/csv; charset=utf-8"},
+		{"XLSX", "/api/v1/check", map[string]string{"program_ids": "CFA,fintech"}, reportContentTypes["xlsx"], http.StatusOK, reportContentTypes["xlsx"]},
+		{"推薦 CSV", "/api/v1/recommend", map[string]string{"format": "csv"}, "", http.StatusOK, "text/csv; charset=utf-8"},
+		{"推薦 XLSX", "/api/v1/recommend", map[string]string{"format": "xlsx"}, "", http.StatusOK, reportContentTypes["xlsx"]},
+		{"不支援的格式", "/api/v1/check", map[string]string{"program_ids": "CFA", "format": "docx"}, "", http.StatusBadRequest, "application/json"},
+		{"推薦不支援 PDF", "/api/v1/recommend", map[string]string{"format": "pdf"}, "", http.StatusBadRequest, "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newMultipartRequest(t, "POST", tt.url, files, tt.fields)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("狀態碼 %d，預期 %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Fatalf("Content-Type %q，預期 %q", got, tt.contentType)
+			}
+			body := rec.Body.Bytes()
+			switch tt.contentType {
+			case "application/pdf":
+				if !bytes.HasPrefix(body, []byte("%PDF-")) || !bytes.HasSuffix(body, []byte("%%EOF\n")) {
+					t.Fatalf("回應不是完整的 PDF 檔")
+				}
+				if !bytes.Contains(body, []byte("/FontFile2")) {
+					t.Fatalf("PDF 未嵌入字型")
+				}
+			case "text/html; charset=utf-8":
+				if !bytes.Contains(body, []byte(`<html lang="zh-Hant">`)) || !bytes.Contains(body, []byte("免責聲明")) {
+					t.Fatalf("HTML 報告缺少必要內容")
+				}
+			case "text/csv; charset=utf-8":
+				records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(body, []byte("\ufeff")))).ReadAll()
+				if err != nil {
+					t.Fatalf("CSV 無法解析: %v", err)
+				}
+				if len(records) < 2 {
+					t.Fatalf("CSV 沒有資料列")
+				}
+			case reportContentTypes["xlsx"]:
+				zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+				if err != nil {
+					t.Fatalf("XLSX 無法解析: %v", err)
+				}
+				for _, f := range zr.File {
+					if !strings.HasSuffix(f.Name, ".xml") && !strings.HasSuffix(f.Name, ".rels") {
+						continue
+					}
+					rc, _ := f.Open()
+					dec := xml.NewDecoder(rc)
+					for {
+						if _, err := dec.Token(); err != nil {
+							if err != io.EOF {
+								t.Fatalf("%s 不是合法的 XML: %v", f.Name, err)
+							}
+							break
+						}
+					}
+					rc.Close()
+				}
+			}
+		})
+	}
+}
+
+// 未設定報表字型時，PDF 請求在檢核前即回傳錯誤，其他格式不受影響
+func TestCheckReportPDFUnavailable(t *testing.T) {
+	router := newRouter(testChecker, defaultConfig(), nil)
+	files := map[string]string{"student_json": "transcript_sample.json"}
+
+	for _, tt := range []struct {
+		fields map[string]string
+		accept string
+		status int
+	}{
+		{map[string]string{"program_ids": "CFA", "format": "pdf"}, "", http.StatusServiceUnavailable},
+		{map[string]string{"program_ids": "CFA"}, "application/pdf", http.StatusServiceUnavailable},
+		{map[string]string{"program_ids": "CFA", "format": "html"}, "", http.StatusOK},
+	} {
+		req := newMultipartRequest(t, "POST", "/api/v1/check", files, tt.fields)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%v (Accept %q): 狀態碼 %d，預期 %d", tt.fields, tt.accept, rec.Code, tt.status)
+		}
+		if tt.status == http.StatusServiceUnavailable && !strings.Contains(rec.Body.String(), errCodePDFUnavailable) {
+			t.Errorf("錯誤代碼不符: %s", rec.Body.String())
+		}
+	}
+}
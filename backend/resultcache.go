@@ -0,0 +1,179 @@
+package main
+
+import (
+	"cmp"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"internal.company/NCCU-Pro/engine"
+)
+
+// --- 檢核結果快取 ---
+//
+// 學生常以同一份成績檔反覆切換勾選的學程，因此在記憶體中保留各學程的檢核結果一小段時間。
+// 鍵為解析後的課程列表、學生資料與學程目錄版本的雜湊：同一份資料不論以 JSON 或 CSV 上傳皆共用快取，
+// 快取中不保留原始檔案，也從不寫入磁碟。
+
+type resultCacheKey struct {
+	transcript [sha256.Size]byte
+	program    string
+}
+
+type resultCacheEntry struct {
+	key     resultCacheKey
+	result  engine.CheckResult
+	expires time.Time
+}
+
+// 固定大小的 LRU 快取；nil 表示停用 (所有方法皆可安全呼叫)
+type resultCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	version string     // 目前項目所屬的學程目錄版本
+	order   *list.List // 最近使用的項目在前
+	items   map[resultCacheKey]*list.Element
+}
+
+func newResultCache(size int, ttl time.Duration) *resultCache {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &resultCache{
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		order: list.New(),
+		items: make(map[resultCacheKey]*list.Element),
+	}
+}
+
+// 課程列表與學生資料的雜湊 (含學程目錄版本)；課程先排序，使同一份資料不因上傳或解析順序不同而產生不同的鍵
+func transcriptHash(version string, courses []engine.StudentCourse, profile engine.StudentProfile) [sha256.Size]byte {
+	courses = slices.Clone(courses)
+	slices.SortFunc(courses, func(a, b engine.StudentCourse) int {
+		return cmp.Or(
+			strings.Compare(a.Name, b.Name),
+			strings.Compare(a.Semester, b.Semester),
+			strings.Compare(a.Score, b.Score),
+			cmp.Compare(a.Credit, b.Credit),
+		)
+	})
+
+	h := sha256.New()
+	h.Write([]byte(version))
+	h.Write([]byte{0})
+	enc := json.NewEncoder(h)
+	enc.Encode(courses)
+	enc.Encode(profile)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// 學程目錄版本改變時清除所有項目 (呼叫者須持有鎖)
+func (c *resultCache) syncVersion(version string) {
+	if c.version == version {
+		return
+	}
+	c.version = version
+	c.order.Init()
+	clear(c.items)
+}
+
+func (c *resultCache) get(version string, key resultCacheKey) (engine.CheckResult, bool) {
+	if c == nil {
+		return engine.CheckResult{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.syncVersion(version)
+
+	elem, ok := c.items[key]
+	if !ok {
+		resultCacheRequests.inc("miss")
+		return engine.CheckResult{}, false
+	}
+	entry := elem.Value.(*resultCacheEntry)
+	if c.now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		resultCacheRequests.inc("miss")
+		return engine.CheckResult{}, false
+	}
+	c.order.MoveToFront(elem)
+	resultCacheRequests.inc("hit")
+	return entry.result, true
+}
+
+func (c *resultCache) put(version string, key resultCacheKey, result engine.CheckResult) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.syncVersion(version)
+
+	expires := c.now().Add(c.ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*resultCacheEntry)
+		entry.result, entry.expires = result, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&resultCacheEntry{key: key, result: result, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*resultCacheEntry).key)
+	}
+}
+
+type resultCacheContextKey struct{}
+
+// 將檢核結果快取放入請求的 context，供 checkPrograms 使用
+func withResultCache(cache *resultCache) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), resultCacheContextKey{}, cache)))
+		})
+	}
+}
+
+// 檢核多個學程，結果依 ids 的順序排列；已快取的學程不重新檢核，其餘以 CheckAll 平行檢核 (ids 須皆存在於目錄中)
+func checkPrograms(r *http.Request, ids []string, courses []engine.StudentCourse, profile engine.StudentProfile) []engine.CheckResult {
+	checker := requestChecker(r)
+	cache, _ := r.Context().Value(resultCacheContextKey{}).(*resultCache)
+	version := checker.Catalog().Version
+	hash := transcriptHash(version, courses, profile)
+
+	results := make([]engine.CheckResult, len(ids))
+	var missed []int // 未快取的學程在 ids 中的位置
+	var missedIDs []string
+	for i, id := range ids {
+		if result, ok := cache.get(version, resultCacheKey{hash, id}); ok {
+			results[i] = result
+			continue
+		}
+		missed = append(missed, i)
+		missedIDs = append(missedIDs, id)
+	}
+	if len(missedIDs) == 0 {
+		return results
+	}
+	for j, result := range checker.CheckAll(missedIDs, courses, profile) {
+		results[missed[j]] = result
+		cache.put(version, resultCacheKey{hash, missedIDs[j]}, result)
+	}
+	return results
+}
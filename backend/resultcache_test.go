@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"internal.company/NCCU-Pro/engine"
+)
+
+func TestResultCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := newResultCache(2, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	hash := transcriptHash("v1", []engine.StudentCourse{{Name: "經濟學", Credit: 3, Score: "85", IsPassed: true}}, engine.StudentProfile{})
+	key := func(program string) resultCacheKey { return resultCacheKey{hash, program} }
+	for _, id := range []string{"CFA", "fintech"} {
+		cache.put("v1", key(id), engine.CheckResult{ProgramName: id})
+	}
+	if got, ok := cache.get("v1", key("CFA")); !ok || got.ProgramName != "CFA" {
+		t.Fatalf("取得 CFA: %v %v", got, ok)
+	}
+
+	// 超過容量時移除最久未使用的項目 (fintech)
+	cache.put("v1", key("museum"), engine.CheckResult{ProgramName: "museum"})
+	if _, ok := cache.get("v1", key("fintech")); ok {
+		t.Error("fintech 應已被移除")
+	}
+	if _, ok := cache.get("v1", key("CFA")); !ok {
+		t.Error("CFA 剛使用過，不應被移除")
+	}
+
+	// 逾時
+	now = now.Add(2 * time.Minute)
+	if _, ok := cache.get("v1", key("CFA")); ok {
+		t.Error("逾時的項目不應回傳")
+	}
+
+	// 學程目錄版本改變時清除所有項目
+	cache.put("v1", key("CFA"), engine.CheckResult{ProgramName: "CFA"})
+	if _, ok := cache.get("v2", key("CFA")); ok || cache.order.Len() != 0 {
+		t.Errorf("版本改變後仍有 %d 個項目", cache.order.Len())
+	}
+
+	// 課程或學生資料不同時雜湊不同
+	other := transcriptHash("v1", []engine.StudentCourse{{Name: "經濟學", Credit: 3, Score: "60", IsPassed: true}}, engine.StudentProfile{})
+	if other == hash || transcriptHash("v1", nil, engine.StudentProfile{Major: "財務管理學系"}) == transcriptHash("v1", nil, engine.StudentProfile{}) {
+		t.Error("不同的資料應有不同的雜湊")
+	}
+
+	var disabled *resultCache
+	disabled.put("v1", key("CFA"), engine.CheckResult{})
+	if _, ok := disabled.get("v1", key("CFA")); ok {
+		t.Error("停用的快取不應回傳結果")
+	}
+}
+
+// 同一份課程列表不論順序都對應同一個雜湊 (不修改呼叫者的切片)
+func TestTranscriptHashIgnoresCourseOrder(t *testing.T) {
+	courses := []engine.StudentCourse{
+		{Name: "經濟學", Credit: 3, Score: "85", Semester: "111-1", IsPassed: true},
+		{Name: "會計學", Credit: 3, Score: "90", Semester: "111-1", IsPassed: true},
+		{Name: "經濟學", Credit: 3, Score: "55", Semester: "110-2"},
+		{Name: "統計學", Credit: 2, Score: "78", Semester: "111-2", IsPassed: true},
+		{Name: "統計學", Credit: 3, Score: "78", Semester: "111-2", IsPassed: true},
+	}
+	want := transcriptHash("v1", courses, engine.StudentProfile{})
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 10 {
+		shuffled := slices.Clone(courses)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		before := slices.Clone(shuffled)
+		if got := transcriptHash("v1", shuffled, engine.StudentProfile{}); got != want {
+			t.Fatalf("順序 %v 的雜湊不同", shuffled)
+		}
+		if !slices.Equal(shuffled, before) {
+			t.Fatal("transcriptHash 不應修改傳入的課程列表")
+		}
+	}
+}
+
+func cacheCount(result string) float64 {
+	resultCacheRequests.mu.Lock()
+	defer resultCacheRequests.mu.Unlock()
+	return resultCacheRequests.values[result]
+}
+
+func TestCheckUsesResultCache(t *testing.T) {
+	router := newRouter(testChecker, defaultConfig(), nil)
+	files := map[string]string{"student_json": "transcript_sample.json"}
+	check := func(ids string) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newMultipartRequest(t, "POST", "/api/v1/check", files, map[string]string{"program_ids": ids}))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("狀態碼 %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec.Body.String()
+	}
+
+	hits, misses := cacheCount("hit"), cacheCount("miss")
+	first := check("CFA")
+	check("CFA,fintech")
+	if got := cacheCount("hit") - hits; got != 1 {
+		t.Errorf("快取命中 %v 次，預期 1", got)
+	}
+	if got := cacheCount("miss") - misses; got != 2 {
+		t.Errorf("快取未命中 %v 次，預期 2", got)
+	}
+	if again := check("CFA"); again != first {
+		t.Error("快取的結果與重新檢核的結果不同")
+	}
+}
+
+// 推薦端點與檢核端點共用各學程的快取項目，結果與直接呼叫 Recommend 相同
+func TestRecommendUsesResultCache(t *testing.T) {
+	router := newRouter(testChecker, defaultConfig(), nil)
+	files := map[string]string{"student_json": "transcript_sample.json"}
+	post := func(url string, fields map[string]string) []byte {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newMultipartRequest(t, "POST", url, files, fields))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("狀態碼 %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec.Body.Bytes()
+	}
+
+	data, err := os.ReadFile("testdata/transcript_sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript, err := testChecker.ParseTranscript(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := json.Marshal(testChecker.Recommend(transcript.Courses, transcript.Profile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	programs := float64(len(testChecker.Catalog().IDs()))
+	hits, misses := cacheCount("hit"), cacheCount("miss")
+	first := post("/api/v1/recommend", nil)
+	if got := cacheCount("miss") - misses; got != programs {
+		t.Errorf("首次推薦未命中 %v 次，預期 %v", got, programs)
+	}
+	second := post("/api/v1/recommend", nil)
+	post("/api/v1/check", map[string]string{"program_ids": "CFA,fintech"})
+	if got := cacheCount("hit") - hits; got != programs+2 {
+		t.Errorf("快取命中 %v 次，預期 %v", got, programs+2)
+	}
+	for _, body := range [][]byte{first, second} {
+		if !bytes.Equal(bytes.TrimSpace(body), want) {
+			t.Errorf("推薦結果與 Recommend 不同:\n%s\n預期:\n%s", body, want)
+		}
+	}
+}